@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
 	"miren.dev/linear-issue-bridge/internal/github"
 	"miren.dev/linear-issue-bridge/internal/linearapi"
+	"miren.dev/linear-issue-bridge/internal/retry"
 )
 
 func main() {
@@ -22,13 +24,32 @@ func main() {
 
 func run() error {
 	var (
-		apply  bool
-		repo   string
-		gitDir string
+		apply      bool
+		repo       string
+		gitDir     string
+		gitTimeout time.Duration
+		policy     retry.Policy
+		ghRPS      float64
+		foldLabels bool
+		labelColor string
+		workers    int
+		minNumber  int
+		authors    github.AuthorFilter
+		skipLogins string
 	)
 	flag.BoolVar(&apply, "apply", false, "actually apply labels (default is dry-run)")
 	flag.StringVar(&repo, "repo", "mirendev/runtime", "GitHub owner/repo to scan")
 	flag.StringVar(&gitDir, "git-dir", ".", "local git clone to scan for commit messages")
+	flag.DurationVar(&gitTimeout, "git-timeout", github.DefaultGitTimeout, "time limit for reading the git log (0 for none)")
+	flag.IntVar(&policy.MaxRetries, "max-retries", retry.Default.MaxRetries, "times to retry a failed GitHub or Linear request")
+	flag.DurationVar(&policy.Base, "retry-base", retry.Default.Base, "wait before the first retry; doubles on each later one")
+	flag.Float64Var(&ghRPS, "github-rps", 0, "maximum GitHub API requests per second (0 for no limit)")
+	flag.BoolVar(&foldLabels, "labels-ignore-case", false, "match label names regardless of case")
+	flag.StringVar(&labelColor, "label-color", linearapi.DefaultLabelColor, "color of the public label, if the team doesn't have one yet and it is created")
+	flag.IntVar(&workers, "concurrency", defaultConcurrency, "issues to label at once; all of them wait when Linear's rate limit runs out")
+	flag.IntVar(&minNumber, "min-number", 0, "skip issues numbered below this, e.g. 500 to leave MIR-1 through MIR-499 alone")
+	flag.BoolVar(&authors.Bots, "skip-bots", false, "ignore pull requests and commits by bots (logins ending in [bot])")
+	flag.StringVar(&skipLogins, "skip-authors", "", "comma-separated logins whose pull requests and commits are ignored")
 	flag.Parse()
 
 	apiKey := os.Getenv("LINEAR_API_KEY")
@@ -41,9 +62,13 @@ func run() error {
 		return fmt.Errorf("LINEAR_TEAM_KEY is required")
 	}
 
-	ghToken := os.Getenv("GITHUB_TOKEN")
-	if ghToken == "" {
-		ghToken = ghAuthToken()
+	if workers < 1 {
+		return fmt.Errorf("-concurrency must be at least 1, got %d", workers)
+	}
+
+	publicLabel := os.Getenv("PUBLIC_LABEL")
+	if publicLabel == "" {
+		publicLabel = linearapi.DefaultPublicLabel
 	}
 
 	parts := strings.SplitN(repo, "/", 2)
@@ -53,42 +78,161 @@ func run() error {
 
 	ctx := context.Background()
 
-	scanner := github.NewRepoScanner(ghToken, parts[0], parts[1])
+	scanner := github.NewRepoScanner(github.ResolveToken(), parts[0], parts[1])
 	scanner.SetGitDir(gitDir)
-
-	identifiers, err := scanner.ScanRepo(ctx, teamKey)
-	if err != nil {
-		return fmt.Errorf("scan repo: %w", err)
+	scanner.SetGitTimeout(gitTimeout)
+	for _, login := range strings.Split(skipLogins, ",") {
+		if login = strings.TrimSpace(login); login != "" {
+			authors.Logins = append(authors.Logins, login)
+		}
+	}
+	scanner.SetAuthorFilter(authors)
+	scanner.SetRetryPolicy(policy)
+	if ghRPS > 0 {
+		scanner.SetRateLimiter(github.NewRateLimiter(ghRPS, 1))
 	}
 
-	slog.Info("scan complete", "identifiers", len(identifiers))
-
+	// Identifiers are labeled as the scan finds them, so labeling a large
+	// repo starts right away and the full list is never held in memory.
+	mins := github.MinNumbers{strings.ToUpper(teamKey): minNumber}
+	found := 0
 	if !apply {
 		fmt.Println("dry-run: would apply public label to:")
-		for _, id := range identifiers {
-			fmt.Printf("  %s\n", id)
+		err := scanner.ScanRepoFunc(ctx, teamKey, func(id string) error {
+			if mins.Allows(id) {
+				found++
+				fmt.Printf("  %s\n", id)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("scan repo: %w", err)
 		}
-		fmt.Printf("\nre-run with -apply to label these issues\n")
+		fmt.Printf("\nre-run with -apply to label these %d issues\n", found)
 		return nil
 	}
 
 	client := linearapi.NewClient(apiKey)
+	client.SetRetryPolicy(policy)
+	client.SetCaseInsensitiveLabels(foldLabels)
+	client.SetPublicLabel(publicLabel)
 	labeler := linearapi.NewPublicLabeler(client, teamKey)
+	labeler.SetLabelColor(labelColor)
+	pool := newLabelPool(ctx, client, labeler, workers)
 
-	for i, id := range identifiers {
-		if err := labeler.EnsurePublicLabel(ctx, id); err != nil {
-			return fmt.Errorf("label %s (%d/%d): %w", id, i+1, len(identifiers), err)
+	scanErr := scanner.ScanRepoFunc(ctx, teamKey, func(id string) error {
+		if mins.Allows(id) {
+			found++
+			pool.label(id)
 		}
+		return nil
+	})
+	results, failures := pool.wait()
+
+	slog.Info("backfill complete",
+		"identifiers", found,
+		"labeled", results[linearapi.LabelApplied],
+		"already_public", results[linearapi.LabelAlreadyPublic],
+		"skipped", results[linearapi.LabelSkipped],
+		"not_found", results[linearapi.LabelNotFound],
+		"failed", len(failures),
+	)
+	for _, f := range failures {
+		slog.Error("failed to label issue", "identifier", f.identifier, "error", f.err)
+	}
+	if scanErr != nil {
+		return fmt.Errorf("scan repo: %w", scanErr)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d identifiers failed to label", len(failures), found)
 	}
-
-	slog.Info("backfill complete", "labeled", len(identifiers))
 	return nil
 }
 
-func ghAuthToken() string {
-	out, err := exec.Command("gh", "auth", "token").Output()
+// defaultConcurrency is how many issues are labeled at once by default. The
+// workers share the client, so when Linear's rate limit runs out they all
+// wait for it to reset.
+const defaultConcurrency = 4
+
+// labelPool labels identifiers on a fixed number of workers, counting the
+// results and collecting failures instead of stopping at the first one.
+type labelPool struct {
+	client  *linearapi.Client
+	labeler *linearapi.PublicLabeler
+	ids     chan string
+	wg      sync.WaitGroup
+
+	mu        sync.Mutex
+	results   map[linearapi.LabelResult]int
+	failures  []labelFailure
+	processed int
+}
+
+type labelFailure struct {
+	identifier string
+	err        error
+}
+
+func newLabelPool(ctx context.Context, client *linearapi.Client, labeler *linearapi.PublicLabeler, workers int) *labelPool {
+	p := &labelPool{
+		client:  client,
+		labeler: labeler,
+		ids:     make(chan string),
+		results: make(map[linearapi.LabelResult]int),
+	}
+	for range workers {
+		p.wg.Go(func() {
+			for id := range p.ids {
+				p.do(ctx, id)
+			}
+		})
+	}
+	return p
+}
+
+// label hands id to a worker, waiting for a free one so the scan doesn't run
+// far ahead of labeling.
+func (p *labelPool) label(id string) {
+	p.ids <- id
+}
+
+func (p *labelPool) do(ctx context.Context, id string) {
+	result, err := p.labeler.EnsurePublicLabel(ctx, id)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	if err != nil {
-		return ""
+		p.failures = append(p.failures, labelFailure{id, err})
+	} else {
+		p.results[result]++
+	}
+	if p.processed++; p.processed%progressEvery == 0 {
+		logProgress(p.client, p.processed)
 	}
-	return strings.TrimSpace(string(out))
+}
+
+// wait finishes labeling the queued identifiers and returns the outcome.
+func (p *labelPool) wait() (map[linearapi.LabelResult]int, []labelFailure) {
+	close(p.ids)
+	p.wg.Wait()
+	return p.results, p.failures
+}
+
+// progressEvery is how many identifiers are labeled between progress logs.
+const progressEvery = 50
+
+// logProgress reports how far labeling has got and how much of Linear's rate
+// limit is left, so long backfills show whether they are about to stall.
+func logProgress(client *linearapi.Client, labeled int) {
+	rl, ok := client.RateLimit()
+	if !ok {
+		slog.Info("labeling progress", "processed", labeled)
+		return
+	}
+	slog.Info("labeling progress",
+		"processed", labeled,
+		"rate_limit_remaining", rl.Remaining,
+		"rate_limit", rl.Limit,
+		"rate_limit_reset", rl.Reset.Format(time.RFC3339),
+	)
 }
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"miren.dev/linear-issue-bridge/internal/env"
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+)
+
+func main() {
+	if err := run(); err != nil {
+		slog.Error("fatal", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		sample     string
+		wantPublic bool
+	)
+	flag.StringVar(&sample, "issue", "", "identifier of an issue to fetch and check the gate for, e.g. MIR-42")
+	flag.BoolVar(&wantPublic, "want-public", true, "whether -issue should be shown publicly")
+	flag.Parse()
+
+	linear, err := env.LoadLinear()
+	if err != nil {
+		return err
+	}
+	st := &selfTest{
+		client:     linear.NewClient(),
+		teamKey:    strings.ToUpper(linear.TeamKey),
+		gate:       linear.GateMode,
+		sample:     sample,
+		wantPublic: wantPublic,
+	}
+	if failed := report(os.Stdout, st.run(context.Background())); failed > 0 {
+		return fmt.Errorf("%d of the checks failed", failed)
+	}
+	return nil
+}
+
+// selfTest checks that the bridge's configuration works against Linear
+// before it is deployed.
+type selfTest struct {
+	client     *linearapi.Client
+	teamKey    string
+	gate       linearapi.GateMode
+	sample     string
+	wantPublic bool
+}
+
+// result is the outcome of one check. Detail describes a pass; Err
+// explains a failure.
+type result struct {
+	Name   string
+	Detail string
+	Err    error
+}
+
+func (s *selfTest) run(ctx context.Context) []result {
+	results := []result{
+		check(ctx, "api key", s.checkAPIKey),
+		check(ctx, "team key", s.checkTeamKey),
+		check(ctx, "public label", s.checkPublicLabel),
+	}
+	if s.sample != "" {
+		results = append(results, check(ctx, "sample issue", s.checkSample))
+	}
+	return results
+}
+
+func check(ctx context.Context, name string, fn func(context.Context) (string, error)) result {
+	detail, err := fn(ctx)
+	return result{Name: name, Detail: detail, Err: err}
+}
+
+func (s *selfTest) checkAPIKey(ctx context.Context) (string, error) {
+	name, err := s.client.FetchViewer(ctx)
+	if err != nil {
+		return "", fmt.Errorf("LINEAR_API_KEY was rejected: %w", err)
+	}
+	return "authenticated as " + name, nil
+}
+
+func (s *selfTest) checkTeamKey(ctx context.Context) (string, error) {
+	name, err := s.client.FetchTeamName(ctx, s.teamKey)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", fmt.Errorf("no team has key %q; check LINEAR_TEAM_KEY", s.teamKey)
+	}
+	return fmt.Sprintf("%s is %s", s.teamKey, name), nil
+}
+
+func (s *selfTest) checkPublicLabel(ctx context.Context) (string, error) {
+	label := s.client.PublicLabel()
+	id, err := s.client.FetchLabelByName(ctx, s.teamKey, label)
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		if s.gate == linearapi.GateDenylist {
+			return "not found, but unused in denylist mode", nil
+		}
+		return fmt.Sprintf("%q not found; it will be created when the first issue is published", label), nil
+	}
+	return "found", nil
+}
+
+func (s *selfTest) checkSample(ctx context.Context) (string, error) {
+	issue, err := s.client.FetchIssue(ctx, s.sample)
+	if err != nil {
+		return "", err
+	}
+	if issue == nil {
+		return "", fmt.Errorf("%s not found", s.sample)
+	}
+	public := s.gate.IsPublic(issue)
+	if public != s.wantPublic {
+		return "", fmt.Errorf("%s public = %t under %s gating, want %t", s.sample, public, s.gate, s.wantPublic)
+	}
+	return fmt.Sprintf("%s public = %t under %s gating", s.sample, public, s.gate), nil
+}
+
+// report prints one line per check and returns how many failed.
+func report(w io.Writer, results []result) int {
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(w, "FAIL  %-13s %v\n", r.Name, r.Err)
+			continue
+		}
+		fmt.Fprintf(w, "PASS  %-13s %s\n", r.Name, r.Detail)
+	}
+	return failed
+}
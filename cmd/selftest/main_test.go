@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+	"miren.dev/linear-issue-bridge/internal/retry"
+)
+
+// fakeLinear answers each query by name with a canned data payload. A query
+// missing from responses gets a GraphQL error.
+func fakeLinear(t *testing.T, responses map[string]string) *linearapi.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		for name, data := range responses {
+			if strings.Contains(req.Query, "query "+name) {
+				fmt.Fprintf(w, `{"data":%s}`, data)
+				return
+			}
+		}
+		fmt.Fprint(w, `{"data":null,"errors":[{"message":"Authentication required"}]}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := linearapi.NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	client.SetRetryPolicy(retry.Policy{})
+	return client
+}
+
+const (
+	viewerOK    = `{"viewer":{"name":"Ada"}}`
+	teamOK      = `{"teams":{"nodes":[{"name":"Miren"}]}}`
+	labelOK     = `{"issueLabels":{"nodes":[{"id":"label-1","name":"public"}]}}`
+	noLabel     = `{"issueLabels":{"nodes":[]}}`
+	publicIssue = `{"issues":{"nodes":[{"id":"i1","identifier":"MIR-42","labels":{"nodes":[{"id":"label-1","name":"public"}]}}]}}`
+)
+
+func TestSelfTest(t *testing.T) {
+	tests := []struct {
+		name       string
+		responses  map[string]string
+		gate       linearapi.GateMode
+		sample     string
+		wantPublic bool
+		want       map[string]string // check name -> substring of its error; "" means pass
+	}{
+		{
+			name:      "all pass",
+			responses: map[string]string{"Viewer": viewerOK, "TeamByKey": teamOK, "LabelByName": labelOK},
+			want:      map[string]string{"api key": "", "team key": "", "public label": ""},
+		},
+		{
+			name:      "bad api key",
+			responses: map[string]string{},
+			want: map[string]string{
+				"api key":      "LINEAR_API_KEY was rejected",
+				"team key":     "Authentication required",
+				"public label": "Authentication required",
+			},
+		},
+		{
+			name:      "unknown team",
+			responses: map[string]string{"Viewer": viewerOK, "TeamByKey": `{"teams":{"nodes":[]}}`, "LabelByName": labelOK},
+			want:      map[string]string{"api key": "", "team key": `no team has key "MIR"`, "public label": ""},
+		},
+		{
+			name:      "missing label",
+			responses: map[string]string{"Viewer": viewerOK, "TeamByKey": teamOK, "LabelByName": noLabel},
+			want:      map[string]string{"api key": "", "team key": "", "public label": ""},
+		},
+		{
+			name:      "missing label in denylist mode",
+			responses: map[string]string{"Viewer": viewerOK, "TeamByKey": teamOK, "LabelByName": noLabel},
+			gate:      linearapi.GateDenylist,
+			want:      map[string]string{"api key": "", "team key": "", "public label": ""},
+		},
+		{
+			name:       "sample gated as expected",
+			responses:  map[string]string{"Viewer": viewerOK, "TeamByKey": teamOK, "LabelByName": labelOK, "IssueByIdentifier": publicIssue},
+			sample:     "MIR-42",
+			wantPublic: true,
+			want:       map[string]string{"api key": "", "team key": "", "public label": "", "sample issue": ""},
+		},
+		{
+			name:      "sample gated wrongly",
+			responses: map[string]string{"Viewer": viewerOK, "TeamByKey": teamOK, "LabelByName": labelOK, "IssueByIdentifier": publicIssue},
+			sample:    "MIR-42",
+			want:      map[string]string{"api key": "", "team key": "", "public label": "", "sample issue": "MIR-42 public = true"},
+		},
+		{
+			name:      "sample not found",
+			responses: map[string]string{"Viewer": viewerOK, "TeamByKey": teamOK, "LabelByName": labelOK, "IssueByIdentifier": `{"issues":{"nodes":[]}}`},
+			sample:    "MIR-42",
+			want:      map[string]string{"api key": "", "team key": "", "public label": "", "sample issue": "MIR-42 not found"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate := tt.gate
+			if gate == "" {
+				gate = linearapi.GateAllowlist
+			}
+			st := &selfTest{
+				client:     fakeLinear(t, tt.responses),
+				teamKey:    "MIR",
+				gate:       gate,
+				sample:     tt.sample,
+				wantPublic: tt.wantPublic,
+			}
+
+			results := st.run(context.Background())
+			if len(results) != len(tt.want) {
+				t.Fatalf("got %d results, want %d: %+v", len(results), len(tt.want), results)
+			}
+			for _, r := range results {
+				want, ok := tt.want[r.Name]
+				switch {
+				case !ok:
+					t.Errorf("unexpected check %q", r.Name)
+				case want == "" && r.Err != nil:
+					t.Errorf("%s failed: %v", r.Name, r.Err)
+				case want != "" && (r.Err == nil || !strings.Contains(r.Err.Error(), want)):
+					t.Errorf("%s error = %v, want it to mention %q", r.Name, r.Err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	var buf bytes.Buffer
+	failed := report(&buf, []result{
+		{Name: "api key", Detail: "authenticated as Ada"},
+		{Name: "public label", Err: fmt.Errorf(`label "public" not found`)},
+	})
+	if failed != 1 {
+		t.Errorf("failed = %d, want 1", failed)
+	}
+	out := buf.String()
+	for _, want := range []string{"PASS  api key", "authenticated as Ada", "FAIL  public label", `label "public" not found`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+}
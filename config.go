@@ -0,0 +1,517 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/cache"
+	"miren.dev/linear-issue-bridge/internal/env"
+	"miren.dev/linear-issue-bridge/internal/github"
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+	"miren.dev/linear-issue-bridge/internal/page"
+)
+
+type config struct {
+	env.Linear
+	Port                string
+	WebhookSecret       string
+	LinearWebhookSecret string
+	UnpublishPrivate    bool
+	AdminToken          string
+	Dev                 bool
+	FathomSiteID        string
+	AssetDir            string
+	AssetHost           string
+	GitHubHosts         []string
+	CacheTTL            time.Duration
+	CacheNegativeTTL    time.Duration
+	CacheStateTTLs      map[string]time.Duration
+	CacheStaleTTL       time.Duration
+	CacheHedgeDelay     time.Duration
+	CacheMaxAge         time.Duration
+	CacheMaxEntries     int
+	CacheRedisURL       string
+	PublicListTTL       time.Duration
+	ListTimeout         time.Duration
+	StaleBanner         time.Duration
+	MaxFetches          int
+	HotIssues           []string
+	CacheWarm           []string
+	HotRefresh          time.Duration
+	HotJitter           time.Duration
+	FetchQueueWait      time.Duration
+	LabelTimeout        time.Duration
+	UnpublishReverts    bool
+	WebhookAsync        bool
+	WebhookQueueSize    int
+	WebhookWorkers      int
+	SkipInProgress      bool
+	VerifyPayloads      bool
+	PublishDelay        time.Duration
+	RepoTeams           map[string][]string
+	MinNumbers          github.MinNumbers
+	FeedContent         page.FeedContent
+	PublicLabelColor    string
+	BareNumbers         bool
+	Aliases             map[string]string
+	Mermaid             bool
+	CopyButtons         bool
+	Comments            bool
+	CanonicalAttachment string
+	CanonicalRedirect   bool
+	RenderMarkdown      bool
+	LinkSchemes         []string
+	Redactions          []*regexp.Regexp
+	HTTP                httpConfig
+}
+
+// httpConfig bounds how long clients may hold connections, so slow or
+// stalled ones can't pile up.
+type httpConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+}
+
+func loadConfig() (*config, error) {
+	linear, err := env.LoadLinear()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &config{
+		Linear:       linear,
+		Port:         os.Getenv("PORT"),
+		FathomSiteID: os.Getenv("FATHOM_SITE_ID"),
+		AssetDir:     os.Getenv("ASSET_DIR"),
+		AssetHost:    os.Getenv("ASSET_HOST"),
+		GitHubHosts:  splitList(os.Getenv("GITHUB_HOSTS")),
+		LinkSchemes:  splitList(os.Getenv("LINK_SCHEMES")),
+		HotIssues:    splitList(strings.ToUpper(os.Getenv("HOT_ISSUES"))),
+		CacheWarm:    splitList(strings.ToUpper(os.Getenv("CACHE_WARM"))),
+	}
+	if len(cfg.LinkSchemes) == 0 {
+		cfg.LinkSchemes = page.DefaultLinkSchemes
+	}
+
+	if cfg.WebhookSecret, err = env.Secret("GITHUB_WEBHOOK_SECRET"); err != nil {
+		return nil, err
+	}
+	if cfg.LinearWebhookSecret, err = env.Secret("LINEAR_WEBHOOK_SECRET"); err != nil {
+		return nil, err
+	}
+	if cfg.AdminToken, err = env.Secret("ADMIN_TOKEN"); err != nil {
+		return nil, err
+	}
+	// The URL can carry the Redis password, so it is read like a secret.
+	if cfg.CacheRedisURL, err = env.Secret("CACHE_REDIS_URL"); err != nil {
+		return nil, err
+	}
+
+	if cfg.Port == "" {
+		cfg.Port = "8080"
+	}
+	if cfg.AssetHost != "" {
+		if u, err := url.Parse(cfg.AssetHost); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid ASSET_HOST %q: want an http(s) URL such as https://cdn.example.com", cfg.AssetHost)
+		}
+	}
+
+	if cfg.FeedContent, err = page.ParseFeedContent(os.Getenv("FEED_CONTENT")); err != nil {
+		return nil, err
+	}
+	if cfg.PublicLabelColor, err = loadLabelColor(); err != nil {
+		return nil, err
+	}
+	if cfg.BareNumbers, err = env.Bool("BARE_ISSUE_NUMBERS", false); err != nil {
+		return nil, err
+	}
+	if cfg.Aliases, err = loadAliases(); err != nil {
+		return nil, err
+	}
+	if cfg.Mermaid, err = env.Bool("MERMAID", false); err != nil {
+		return nil, err
+	}
+	if cfg.Mermaid && !page.MermaidVendored() {
+		return nil, fmt.Errorf("MERMAID is set but this build has no Mermaid bundle; run make vendor-mermaid")
+	}
+	if cfg.CopyButtons, err = env.Bool("CODE_COPY_BUTTONS", false); err != nil {
+		return nil, err
+	}
+	if cfg.Comments, err = env.Bool("SHOW_COMMENTS", false); err != nil {
+		return nil, err
+	}
+	cfg.CanonicalAttachment = os.Getenv("CANONICAL_ATTACHMENT")
+	if cfg.CanonicalRedirect, err = env.Bool("CANONICAL_REDIRECT", false); err != nil {
+		return nil, err
+	}
+	if cfg.CanonicalRedirect && cfg.CanonicalAttachment == "" {
+		return nil, fmt.Errorf("CANONICAL_REDIRECT requires CANONICAL_ATTACHMENT")
+	}
+	if cfg.RenderMarkdown, err = env.Bool("RENDER_MARKDOWN", true); err != nil {
+		return nil, err
+	}
+	if cfg.Redactions, err = loadRedactions(); err != nil {
+		return nil, err
+	}
+	if cfg.HTTP, err = loadHTTPConfig(); err != nil {
+		return nil, err
+	}
+	if cfg.CacheTTL, err = loadCacheTTL(); err != nil {
+		return nil, err
+	}
+	if cfg.CacheNegativeTTL, err = env.Duration("CACHE_NEGATIVE_TTL", cache.DefaultNegativeTTL); err != nil {
+		return nil, err
+	}
+	if cfg.CacheStaleTTL, err = env.Duration("CACHE_STALE_TTL", 0); err != nil {
+		return nil, err
+	}
+	if cfg.CacheHedgeDelay, err = env.Duration("CACHE_HEDGE_DELAY", 0); err != nil {
+		return nil, err
+	}
+	if cfg.CacheMaxAge, err = env.Duration("CACHE_MAX_AGE", cache.DefaultMaxAge); err != nil {
+		return nil, err
+	}
+	if cfg.PublicListTTL, err = env.Duration("PUBLIC_LIST_TTL", cache.DefaultListTTL); err != nil {
+		return nil, err
+	}
+	if cfg.ListTimeout, err = env.Duration("LIST_TIMEOUT", defaultListTimeout); err != nil {
+		return nil, err
+	}
+	if cfg.ListTimeout <= 0 {
+		return nil, fmt.Errorf("LIST_TIMEOUT must be positive, got %s", cfg.ListTimeout)
+	}
+	if cfg.StaleBanner, err = env.Duration("STALE_BANNER_AFTER", 0); err != nil {
+		return nil, err
+	}
+	if cfg.CacheMaxEntries, err = env.Int("CACHE_MAX_ENTRIES", cache.DefaultMaxEntries); err != nil {
+		return nil, err
+	}
+	if cfg.MaxFetches, err = env.Int("MAX_CONCURRENT_FETCHES", 0); err != nil {
+		return nil, err
+	}
+	if cfg.FetchQueueWait, err = env.Duration("FETCH_QUEUE_WAIT", time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.HotRefresh, err = env.Duration("HOT_REFRESH_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.HotJitter, err = env.Duration("HOT_REFRESH_JITTER", 10*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.LabelTimeout, err = env.Duration("WEBHOOK_LABEL_TIMEOUT", github.DefaultLabelTimeout); err != nil {
+		return nil, err
+	}
+	if cfg.UnpublishReverts, err = env.Bool("WEBHOOK_UNPUBLISH_REVERTS", false); err != nil {
+		return nil, err
+	}
+	if cfg.WebhookAsync, err = env.Bool("WEBHOOK_ASYNC", true); err != nil {
+		return nil, err
+	}
+	if cfg.WebhookQueueSize, err = env.Int("WEBHOOK_QUEUE_SIZE", github.DefaultQueueSize); err != nil {
+		return nil, err
+	}
+	if cfg.WebhookWorkers, err = env.Int("WEBHOOK_WORKERS", github.DefaultWorkers); err != nil {
+		return nil, err
+	}
+	if cfg.VerifyPayloads, err = env.Bool("WEBHOOK_VERIFY_PAYLOADS", false); err != nil {
+		return nil, err
+	}
+	if cfg.PublishDelay, err = env.Duration("WEBHOOK_PUBLISH_DELAY", 0); err != nil {
+		return nil, err
+	}
+	if cfg.UnpublishPrivate, err = env.Bool("LINEAR_WEBHOOK_UNPUBLISH_PRIVATE", false); err != nil {
+		return nil, err
+	}
+	if cfg.SkipInProgress, err = env.Bool("WEBHOOK_SKIP_IN_PROGRESS", false); err != nil {
+		return nil, err
+	}
+	if cfg.Dev, err = env.Bool("DEV", false); err != nil {
+		return nil, err
+	}
+	if cfg.RepoTeams, err = loadRepoTeams(); err != nil {
+		return nil, err
+	}
+	if cfg.CacheStateTTLs, err = loadStateTTLs(); err != nil {
+		return nil, err
+	}
+	if cfg.MinNumbers, err = loadMinNumbers(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// LogValue reports the effective configuration with secrets reduced to
+// whether they are set.
+func (c *config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("port", c.Port),
+		slog.String("team_key", c.TeamKey),
+		slog.String("gate_mode", string(c.GateMode)),
+		slog.String("feed_content", string(c.FeedContent)),
+		slog.Bool("include_subteams", c.IncludeSubTeams),
+		slog.Int("linear_max_retries", c.Retry.MaxRetries),
+		slog.Duration("linear_retry_base", c.Retry.Base),
+		slog.Bool("lookup_fallback", c.LookupFallback),
+		slog.Bool("labels_case_insensitive", c.FoldLabels),
+		slog.String("public_label", c.PublicLabel),
+		slog.String("public_label_color", c.PublicLabelColor),
+		slog.Bool("bare_issue_numbers", c.BareNumbers),
+		slog.Int("issue_aliases", len(c.Aliases)),
+		slog.String("linear_api_key", secretState(c.APIKey)),
+		slog.String("github_webhook_secret", secretState(c.WebhookSecret)),
+		slog.Bool("github_webhook", c.WebhookSecret != ""),
+		slog.String("linear_webhook_secret", secretState(c.LinearWebhookSecret)),
+		slog.Bool("linear_webhook_unpublish_private", c.UnpublishPrivate),
+		slog.String("admin_token", secretState(c.AdminToken)),
+		slog.Bool("dev", c.Dev),
+		slog.Bool("fathom", c.FathomSiteID != ""),
+		slog.String("asset_dir", c.AssetDir),
+		slog.String("asset_host", c.AssetHost),
+		slog.Bool("mermaid", c.Mermaid),
+		slog.Bool("code_copy_buttons", c.CopyButtons),
+		slog.Bool("show_comments", c.Comments),
+		slog.String("canonical_attachment", c.CanonicalAttachment),
+		slog.Bool("canonical_redirect", c.CanonicalRedirect),
+		slog.Bool("render_markdown", c.RenderMarkdown),
+		slog.Any("link_schemes", c.LinkSchemes),
+		slog.Int("redact_patterns", len(c.Redactions)),
+		slog.Any("github_hosts", c.GitHubHosts),
+		slog.Duration("cache_ttl", c.CacheTTL),
+		slog.Duration("cache_negative_ttl", c.CacheNegativeTTL),
+		slog.Any("cache_state_ttls", c.CacheStateTTLs),
+		slog.Duration("cache_stale_ttl", c.CacheStaleTTL),
+		slog.Duration("cache_hedge_delay", c.CacheHedgeDelay),
+		slog.Duration("cache_max_age", c.CacheMaxAge),
+		slog.Int("cache_max_entries", c.CacheMaxEntries),
+		slog.String("cache_redis_url", secretState(c.CacheRedisURL)),
+		slog.Duration("public_list_ttl", c.PublicListTTL),
+		slog.Duration("list_timeout", c.ListTimeout),
+		slog.Duration("stale_banner_after", c.StaleBanner),
+		slog.Int("max_concurrent_fetches", c.MaxFetches),
+		slog.Duration("fetch_queue_wait", c.FetchQueueWait),
+		slog.Any("hot_issues", c.HotIssues),
+		slog.Int("cache_warm", len(c.CacheWarm)),
+		slog.Duration("hot_refresh_interval", c.HotRefresh),
+		slog.Duration("hot_refresh_jitter", c.HotJitter),
+		slog.Duration("webhook_label_timeout", c.LabelTimeout),
+		slog.Bool("webhook_unpublish_reverts", c.UnpublishReverts),
+		slog.Any("webhook_repo_teams", c.RepoTeams),
+		slog.Bool("webhook_async", c.WebhookAsync),
+		slog.Int("webhook_queue_size", c.WebhookQueueSize),
+		slog.Int("webhook_workers", c.WebhookWorkers),
+		slog.Bool("webhook_skip_in_progress", c.SkipInProgress),
+		slog.Bool("webhook_verify_payloads", c.VerifyPayloads),
+		slog.Duration("webhook_publish_delay", c.PublishDelay),
+		slog.Any("publish_min_numbers", c.MinNumbers),
+		slog.Duration("http_read_header_timeout", c.HTTP.ReadHeaderTimeout),
+		slog.Duration("http_read_timeout", c.HTTP.ReadTimeout),
+		slog.Duration("http_write_timeout", c.HTTP.WriteTimeout),
+		slog.Duration("http_idle_timeout", c.HTTP.IdleTimeout),
+		slog.Int("http_max_header_bytes", c.HTTP.MaxHeaderBytes),
+	)
+}
+
+func loadHTTPConfig() (httpConfig, error) {
+	var (
+		hc  httpConfig
+		err error
+	)
+	if hc.ReadHeaderTimeout, err = env.Duration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second); err != nil {
+		return hc, err
+	}
+	if hc.ReadTimeout, err = env.Duration("HTTP_READ_TIMEOUT", 15*time.Second); err != nil {
+		return hc, err
+	}
+	// Issue pages may wait up to 10s on Linear before writing anything.
+	if hc.WriteTimeout, err = env.Duration("HTTP_WRITE_TIMEOUT", 30*time.Second); err != nil {
+		return hc, err
+	}
+	if hc.IdleTimeout, err = env.Duration("HTTP_IDLE_TIMEOUT", 2*time.Minute); err != nil {
+		return hc, err
+	}
+	if hc.MaxHeaderBytes, err = env.Int("HTTP_MAX_HEADER_BYTES", 64<<10); err != nil {
+		return hc, err
+	}
+	return hc, nil
+}
+
+func secretState(s string) string {
+	if s == "" {
+		return "unset"
+	}
+	return "set"
+}
+
+// splitList parses a comma-separated env value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// teamKeys lists the teams whose issues the bridge serves: LINEAR_TEAM_KEY
+// first, then every other team WEBHOOK_REPO_TEAMS maps a repo to, so issues
+// the GitHub webhook publishes can be viewed.
+func (c *config) teamKeys() []string {
+	keys := []string{strings.ToUpper(c.TeamKey)}
+	for _, repoKeys := range c.RepoTeams {
+		for _, key := range repoKeys {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	slices.Sort(keys[1:])
+	return keys
+}
+
+// loadRepoTeams parses WEBHOOK_REPO_TEAMS, a comma-separated list of
+// repo=KEY pairs such as "org/frontend=WEB,org/backend=API". A repo may be
+// listed more than once to map it to several teams.
+func loadRepoTeams() (map[string][]string, error) {
+	items := splitList(os.Getenv("WEBHOOK_REPO_TEAMS"))
+	if len(items) == 0 {
+		return nil, nil
+	}
+	repoTeams := make(map[string][]string)
+	for _, item := range items {
+		repo, key, ok := strings.Cut(item, "=")
+		repo, key = strings.TrimSpace(repo), strings.TrimSpace(key)
+		if !ok || repo == "" || key == "" {
+			return nil, fmt.Errorf("invalid WEBHOOK_REPO_TEAMS entry %q: want repo=KEY", item)
+		}
+		repoTeams[repo] = append(repoTeams[repo], strings.ToUpper(key))
+	}
+	return repoTeams, nil
+}
+
+// loadAliases parses ISSUE_ALIASES, a comma-separated list of OLD=NEW
+// identifier pairs such as "MIR-42=NEW-7" for issues that moved teams.
+func loadAliases() (map[string]string, error) {
+	items := splitList(strings.ToUpper(os.Getenv("ISSUE_ALIASES")))
+	if len(items) == 0 {
+		return nil, nil
+	}
+	aliases := make(map[string]string, len(items))
+	for _, item := range items {
+		from, to, ok := strings.Cut(item, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid ISSUE_ALIASES entry %q: want OLD=NEW", item)
+		}
+		aliases[from] = to
+	}
+	for from, to := range aliases {
+		if _, ok := aliases[to]; ok {
+			return nil, fmt.Errorf("invalid ISSUE_ALIASES: %s points to %s, which is itself an alias", from, to)
+		}
+	}
+	return aliases, nil
+}
+
+// loadStateTTLs parses CACHE_STATE_TTLS, a comma-separated list of
+// STATE=DURATION pairs keyed by workflow state type, such as
+// "completed=1h,started=2m".
+func loadStateTTLs() (map[string]time.Duration, error) {
+	items := splitList(os.Getenv("CACHE_STATE_TTLS"))
+	if len(items) == 0 {
+		return nil, nil
+	}
+	ttls := make(map[string]time.Duration, len(items))
+	for _, item := range items {
+		state, ttl, ok := strings.Cut(item, "=")
+		d, err := time.ParseDuration(strings.TrimSpace(ttl))
+		if !ok || err != nil || d <= 0 || strings.TrimSpace(state) == "" {
+			return nil, fmt.Errorf("invalid CACHE_STATE_TTLS entry %q: want STATE=DURATION", item)
+		}
+		ttls[strings.ToLower(strings.TrimSpace(state))] = d
+	}
+	return ttls, nil
+}
+
+// loadMinNumbers parses PUBLISH_MIN_NUMBERS, a comma-separated list of
+// KEY=N pairs such as "MIR=500,WEB=20".
+func loadMinNumbers() (github.MinNumbers, error) {
+	items := splitList(os.Getenv("PUBLISH_MIN_NUMBERS"))
+	if len(items) == 0 {
+		return nil, nil
+	}
+	mins := make(github.MinNumbers, len(items))
+	for _, item := range items {
+		key, num, ok := strings.Cut(item, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(num))
+		if !ok || err != nil || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid PUBLISH_MIN_NUMBERS entry %q: want KEY=N", item)
+		}
+		mins[strings.ToUpper(strings.TrimSpace(key))] = n
+	}
+	return mins, nil
+}
+
+// loadRedactions compiles REDACT_PATTERNS, a whitespace-separated list of
+// regular expressions (use \s to match a space). Unset keeps the defaults.
+func loadRedactions() ([]*regexp.Regexp, error) {
+	fields := strings.Fields(os.Getenv("REDACT_PATTERNS"))
+	if len(fields) == 0 {
+		return page.DefaultRedactions, nil
+	}
+	patterns := make([]*regexp.Regexp, len(fields))
+	for i, f := range fields {
+		re, err := regexp.Compile(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDACT_PATTERNS: %w", err)
+		}
+		patterns[i] = re
+	}
+	return patterns, nil
+}
+
+// defaultMinCacheTTL keeps a tiny CACHE_TTL from turning nearly every request
+// into a Linear API call.
+const defaultMinCacheTTL = 10 * time.Second
+
+// loadCacheTTL reads CACHE_TTL, raising it to CACHE_MIN_TTL if set lower.
+func loadCacheTTL() (time.Duration, error) {
+	ttl, err := env.Duration("CACHE_TTL", cache.DefaultTTL)
+	if err != nil {
+		return 0, err
+	}
+	minTTL, err := env.Duration("CACHE_MIN_TTL", defaultMinCacheTTL)
+	if err != nil {
+		return 0, err
+	}
+	if ttl < minTTL {
+		slog.Warn("CACHE_TTL below minimum, clamping", "configured", ttl, "min", minTTL)
+		return minTTL, nil
+	}
+	return ttl, nil
+}
+
+var labelColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// loadLabelColor reads PUBLIC_LABEL_COLOR, the color the public label is
+// created with in teams that don't have it yet.
+func loadLabelColor() (string, error) {
+	v := os.Getenv("PUBLIC_LABEL_COLOR")
+	if v == "" {
+		return linearapi.DefaultLabelColor, nil
+	}
+	if !labelColorPattern.MatchString(v) {
+		return "", fmt.Errorf("invalid PUBLIC_LABEL_COLOR %q: want a hex color such as %s", v, linearapi.DefaultLabelColor)
+	}
+	return v, nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/cache"
+	"miren.dev/linear-issue-bridge/internal/env"
+	"miren.dev/linear-issue-bridge/internal/github"
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+	"miren.dev/linear-issue-bridge/internal/page"
+)
+
+func TestConfigLogRedactsSecrets(t *testing.T) {
+	t.Setenv("LINEAR_API_KEY", "lin_api_supersecret")
+	t.Setenv("LINEAR_TEAM_KEY", "MIR")
+	t.Setenv("GITHUB_WEBHOOK_SECRET", "hook-secret-value")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("effective config", "config", cfg)
+	out := buf.String()
+
+	for _, secret := range []string{"lin_api_supersecret", "hook-secret-value"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("config dump leaked secret %q: %s", secret, out)
+		}
+	}
+	for _, want := range []string{`"linear_api_key":"set"`, `"github_webhook_secret":"set"`, `"team_key":"MIR"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("config dump missing %s: %s", want, out)
+		}
+	}
+}
+
+func TestConfigLogUnsetSecret(t *testing.T) {
+	t.Setenv("LINEAR_API_KEY", "key")
+	t.Setenv("LINEAR_TEAM_KEY", "MIR")
+	t.Setenv("GITHUB_WEBHOOK_SECRET", "")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("effective config", "config", cfg)
+
+	if !strings.Contains(buf.String(), `"github_webhook_secret":"unset"`) {
+		t.Errorf("expected unset webhook secret in %s", buf.String())
+	}
+}
+
+func TestConfigPublicLabel(t *testing.T) {
+	t.Setenv("LINEAR_API_KEY", "key")
+	t.Setenv("LINEAR_TEAM_KEY", "MIR")
+	for _, tt := range []struct{ env, want string }{
+		{"", "public"},
+		{"published", "published"},
+	} {
+		t.Setenv("PUBLIC_LABEL", tt.env)
+		cfg, err := loadConfig()
+		if err != nil {
+			t.Fatalf("loadConfig: %v", err)
+		}
+		if cfg.PublicLabel != tt.want {
+			t.Errorf("PUBLIC_LABEL=%q: PublicLabel = %q, want %q", tt.env, cfg.PublicLabel, tt.want)
+		}
+	}
+}
+
+func TestLoadCacheTTL(t *testing.T) {
+	tests := []struct {
+		name   string
+		ttl    string
+		minTTL string
+		want   time.Duration
+	}{
+		{"default", "", "", cache.DefaultTTL},
+		{"tiny value clamped", "1ms", "", defaultMinCacheTTL},
+		{"reasonable value unchanged", "2m", "", 2 * time.Minute},
+		{"configured minimum", "30s", "1m", time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CACHE_TTL", tt.ttl)
+			t.Setenv("CACHE_MIN_TTL", tt.minTTL)
+
+			got, err := loadCacheTTL()
+			if err != nil {
+				t.Fatalf("loadCacheTTL: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("loadCacheTTL = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRedactions(t *testing.T) {
+	t.Setenv("REDACT_PATTERNS", "")
+	got, err := loadRedactions()
+	if err != nil {
+		t.Fatalf("loadRedactions: %v", err)
+	}
+	if len(got) != len(page.DefaultRedactions) {
+		t.Errorf("unset REDACT_PATTERNS gave %d patterns, want the %d defaults", len(got), len(page.DefaultRedactions))
+	}
+
+	t.Setenv("REDACT_PATTERNS", `internal\.example\.com  secret-\d+`)
+	got, err = loadRedactions()
+	if err != nil {
+		t.Fatalf("loadRedactions: %v", err)
+	}
+	if len(got) != 2 || got[1].String() != `secret-\d+` {
+		t.Errorf("loadRedactions = %v, want the two configured patterns", got)
+	}
+
+	t.Setenv("REDACT_PATTERNS", `(unclosed`)
+	if _, err := loadRedactions(); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestLoadRepoTeams(t *testing.T) {
+	t.Setenv("WEBHOOK_REPO_TEAMS", "org/frontend=web, org/backend=API,org/frontend=MOB")
+	got, err := loadRepoTeams()
+	if err != nil {
+		t.Fatalf("loadRepoTeams: %v", err)
+	}
+	want := map[string][]string{
+		"org/frontend": {"WEB", "MOB"},
+		"org/backend":  {"API"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadRepoTeams = %v, want %v", got, want)
+	}
+
+	t.Setenv("WEBHOOK_REPO_TEAMS", "org/frontend")
+	if _, err := loadRepoTeams(); err == nil {
+		t.Error("expected error for entry without a team key")
+	}
+}
+
+func TestConfigTeamKeys(t *testing.T) {
+	cfg := &config{Linear: env.Linear{TeamKey: "mir"}, RepoTeams: map[string][]string{
+		"org/frontend": {"WEB", "MIR"},
+		"org/backend":  {"API"},
+	}}
+	if got, want := cfg.teamKeys(), []string{"MIR", "API", "WEB"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("teamKeys = %v, want %v", got, want)
+	}
+}
+
+func TestLoadMinNumbers(t *testing.T) {
+	t.Setenv("PUBLISH_MIN_NUMBERS", "mir=500, WEB=20")
+	got, err := loadMinNumbers()
+	if err != nil {
+		t.Fatalf("loadMinNumbers: %v", err)
+	}
+	if want := (github.MinNumbers{"MIR": 500, "WEB": 20}); !reflect.DeepEqual(got, want) {
+		t.Errorf("loadMinNumbers = %v, want %v", got, want)
+	}
+
+	t.Setenv("PUBLISH_MIN_NUMBERS", "MIR=lots")
+	if _, err := loadMinNumbers(); err == nil {
+		t.Error("expected error for non-numeric minimum")
+	}
+}
+
+func TestLoadStateTTLs(t *testing.T) {
+	t.Setenv("CACHE_STATE_TTLS", "Completed=1h, started=2m")
+	got, err := loadStateTTLs()
+	if err != nil {
+		t.Fatalf("loadStateTTLs: %v", err)
+	}
+	if want := map[string]time.Duration{"completed": time.Hour, "started": 2 * time.Minute}; !reflect.DeepEqual(got, want) {
+		t.Errorf("loadStateTTLs = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"completed", "completed=soon", "=1h", "started=0s"} {
+		t.Setenv("CACHE_STATE_TTLS", bad)
+		if _, err := loadStateTTLs(); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLoadLabelColor(t *testing.T) {
+	t.Setenv("PUBLIC_LABEL_COLOR", "")
+	if got, err := loadLabelColor(); err != nil || got != linearapi.DefaultLabelColor {
+		t.Errorf("loadLabelColor() = %q, %v; want the default", got, err)
+	}
+
+	t.Setenv("PUBLIC_LABEL_COLOR", "#5E6AD2")
+	if got, err := loadLabelColor(); err != nil || got != "#5E6AD2" {
+		t.Errorf("loadLabelColor() = %q, %v; want #5E6AD2", got, err)
+	}
+
+	for _, bad := range []string{"green", "5e6ad2", "#fff"} {
+		t.Setenv("PUBLIC_LABEL_COLOR", bad)
+		if _, err := loadLabelColor(); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLoadAliases(t *testing.T) {
+	t.Setenv("ISSUE_ALIASES", "mir-42=new-7, MIR-43=NEW-8")
+	got, err := loadAliases()
+	if err != nil {
+		t.Fatalf("loadAliases: %v", err)
+	}
+	if want := map[string]string{"MIR-42": "NEW-7", "MIR-43": "NEW-8"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("loadAliases = %v, want %v", got, want)
+	}
+
+	t.Setenv("ISSUE_ALIASES", "MIR-42=NEW-7,NEW-7=MIR-42")
+	if _, err := loadAliases(); err == nil {
+		t.Error("expected error for an alias pointing at another alias")
+	}
+}
@@ -2,7 +2,13 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"miren.dev/linear-issue-bridge/internal/linearapi"
@@ -10,51 +16,589 @@ import (
 
 const DefaultTTL = 5 * time.Minute
 
-type entry struct {
-	issue     *linearapi.Issue
-	fetchedAt time.Time
+// DefaultNegativeTTL is how long a lookup that found no issue is cached. It
+// is shorter than DefaultTTL so newly created or moved issues show up soon.
+const DefaultNegativeTTL = 30 * time.Second
+
+// DefaultMaxAge is how old an entry may get before it is no longer served as
+// a stale fallback.
+const DefaultMaxAge = 24 * time.Hour
+
+// DefaultMaxEntries is how many identifiers the cache holds before evicting
+// the least recently used.
+const DefaultMaxEntries = 10000
+
+// warmConcurrency bounds how many fetches Warm runs at once.
+const warmConcurrency = 8
+
+// refreshTimeout bounds background refreshes, which outlive the request
+// that started them.
+const refreshTimeout = 10 * time.Second
+
+// ErrBusy is returned when a fetch couldn't start because the concurrent
+// fetch limit was reached and no slot freed up in time.
+var ErrBusy = errors.New("too many concurrent fetches")
+
+// Status describes how a Get was served.
+type Status string
+
+const (
+	Hit   Status = "HIT"   // fresh entry from the cache
+	Miss  Status = "MISS"  // fetched from Linear for this request
+	Stale Status = "STALE" // expired entry served while a refresh is pending or failing
+)
+
+// Meta describes how a Get was served and how old the result is.
+type Meta struct {
+	Status    Status
+	FetchedAt time.Time
 }
 
 type IssueFetcher interface {
 	FetchIssue(ctx context.Context, identifier string) (*linearapi.Issue, error)
 }
 
+// BatchFetcher is implemented by fetchers that can look up several issues in
+// one round trip. A batch may resolve fewer identifiers than FetchIssue would,
+// so the cache confirms the ones missing from the result with FetchIssue
+// before remembering them as not found.
+type BatchFetcher interface {
+	FetchIssues(ctx context.Context, identifiers []string) (map[string]*linearapi.Issue, error)
+}
+
 type Cache struct {
-	fetcher IssueFetcher
-	ttl     time.Duration
+	fetcher     IssueFetcher
+	ttl         time.Duration
+	negativeTTL time.Duration
+	stateTTLs   map[string]time.Duration // by State.Type
+	staleTTL    time.Duration
+	hedgeDelay  time.Duration
+	maxAge      time.Duration
+
+	// fetchSlots bounds concurrent fetches when non-nil; fetchWait is how
+	// long a fetch may queue for a slot.
+	fetchSlots chan struct{}
+	fetchWait  time.Duration
 
-	mu      sync.RWMutex
-	entries map[string]*entry
+	store Store
+
+	mu         sync.Mutex
+	refreshing map[string]*refresh
+
+	hits        atomic.Int64
+	misses      atomic.Int64
+	stale       atomic.Int64
+	coalesced   atomic.Int64
+	lastSuccess atomic.Int64 // UnixNano of the last successful fetch
 }
 
+type refresh struct {
+	done      chan struct{}
+	callers   int // guarded by Cache.mu
+	issue     *linearapi.Issue
+	fetchedAt time.Time
+	err       error
+}
+
+// Stats counts cache activity since startup.
+type Stats struct {
+	// Hits, Misses, and Stale count lookups by how they were served; see
+	// Status. Misses include lookups whose fetch failed.
+	Hits   int64
+	Misses int64
+	Stale  int64
+
+	// Entries is how many identifiers are cached now, and Evictions how many
+	// were dropped to stay within the entry limit. Both are zero for stores
+	// that don't track them.
+	Entries   int
+	Evictions int64
+
+	// Coalesced is how many fetches were saved by callers joining a fetch
+	// already in flight for the same identifier.
+	Coalesced int64
+
+	// LastSuccess is when a fetch from Linear last succeeded, or the zero
+	// time if none has. Failures leave it alone, so a growing gap means the
+	// cache is only serving what it already had.
+	LastSuccess time.Time
+}
+
+func (c *Cache) Stats() Stats {
+	s := Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Stale:     c.stale.Load(),
+		Coalesced: c.coalesced.Load(),
+	}
+	if l, ok := c.store.(Lister); ok {
+		s.Entries = len(l.Entries())
+	}
+	if e, ok := c.store.(interface{ Evictions() int64 }); ok {
+		s.Evictions = e.Evictions()
+	}
+	if ns := c.lastSuccess.Load(); ns != 0 {
+		s.LastSuccess = time.Unix(0, ns)
+	}
+	return s
+}
+
+// New returns a cache that keeps entries in memory.
 func New(fetcher IssueFetcher, ttl time.Duration) *Cache {
+	return NewWithStore(fetcher, ttl, NewMemoryStore(DefaultMaxEntries))
+}
+
+// NewWithStore returns a cache that keeps entries in store.
+func NewWithStore(fetcher IssueFetcher, ttl time.Duration, store Store) *Cache {
 	return &Cache{
-		fetcher: fetcher,
-		ttl:     ttl,
-		entries: make(map[string]*entry),
+		fetcher:     fetcher,
+		ttl:         ttl,
+		negativeTTL: DefaultNegativeTTL,
+		maxAge:      DefaultMaxAge,
+		store:       store,
+		refreshing:  make(map[string]*refresh),
+	}
+}
+
+// SetMaxEntries bounds how many identifiers the cache holds, evicting the
+// least recently read or stored when it grows past n, so crawlers walking
+// through identifiers can't grow it forever. Zero or less removes the bound.
+// It only applies to the in-memory store.
+func (c *Cache) SetMaxEntries(n int) {
+	if m, ok := c.store.(*MemoryStore); ok {
+		m.SetMaxEntries(n)
+	}
+}
+
+// SetNegativeTTL sets how long a lookup that found no issue stays cached. It
+// never exceeds the TTL of found issues.
+func (c *Cache) SetNegativeTTL(d time.Duration) {
+	c.negativeTTL = d
+}
+
+// SetStateTTLs overrides the TTL for issues by their workflow state type,
+// such as "completed" or "started", so issues that rarely change can be
+// cached longer and ones in progress shorter. Other states keep the TTL.
+func (c *Cache) SetStateTTLs(ttls map[string]time.Duration) {
+	c.stateTTLs = ttls
+}
+
+// ttlFor is how long a fetch of issue stays fresh.
+func (c *Cache) ttlFor(issue *linearapi.Issue) time.Duration {
+	if issue == nil {
+		return min(c.ttl, c.negativeTTL)
+	}
+	if ttl, ok := c.stateTTLs[issue.State.Type]; ok {
+		return ttl
+	}
+	return c.ttl
+}
+
+// fresh reports whether e can be served without refetching.
+func (c *Cache) fresh(e Entry) bool {
+	return time.Since(e.FetchedAt) < c.ttlFor(e.Issue)
+}
+
+// SetStaleTTL enables stale-while-revalidate: an expired entry younger than d
+// is returned immediately, and a background refresh updates it for later
+// readers. A failed refresh keeps the stale entry. Zero disables it; d no
+// longer than the TTL has no effect.
+func (c *Cache) SetStaleTTL(d time.Duration) {
+	c.staleTTL = d
+}
+
+// SetHedgeDelay enables hedged refreshes of expired entries: Get waits up to
+// d for a fresh value and otherwise returns the expired one while the refresh
+// finishes in the background. Zero disables hedging.
+func (c *Cache) SetHedgeDelay(d time.Duration) {
+	c.hedgeDelay = d
+}
+
+// SetMaxAge bounds how old an expired entry may be and still be served while
+// a refresh is pending or failing. Older entries are refetched synchronously,
+// surfacing any fetch error, so readers never see days-old state as current.
+// Zero removes the bound.
+func (c *Cache) SetMaxAge(d time.Duration) {
+	c.maxAge = d
+}
+
+// SetFetchLimit caps how many fetches to Linear run at once across all
+// identifiers, so a burst of cold misses can't exhaust Linear's rate limit.
+// A fetch waits up to wait for a free slot and otherwise fails with ErrBusy.
+// Zero n removes the limit.
+func (c *Cache) SetFetchLimit(n int, wait time.Duration) {
+	if n <= 0 {
+		c.fetchSlots = nil
+		return
+	}
+	c.fetchSlots = make(chan struct{}, n)
+	c.fetchWait = wait
+}
+
+// Warm fetches identifiers that aren't cached or have expired, so their
+// first readers after a deploy don't wait on Linear. A fetcher that supports
+// batching gets them all in one call; otherwise they are fetched up to
+// warmConcurrency at a time. Failures are logged and skipped. It returns
+// once every fetch has finished or ctx is done.
+func (c *Cache) Warm(ctx context.Context, identifiers []string) {
+	var missing []string
+	for _, id := range identifiers {
+		if e, ok := c.store.Get(ctx, id); !ok || !c.fresh(e) {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	if bf, ok := c.fetcher.(BatchFetcher); ok {
+		c.warmBatch(ctx, bf, missing)
+		return
+	}
+
+	sem := make(chan struct{}, warmConcurrency)
+	var wg sync.WaitGroup
+	var warmed, failed atomic.Int64
+	start := time.Now()
+loop:
+	for _, id := range missing {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break loop
+		}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			rf := c.startRefresh(ctx, id, false)
+			select {
+			case <-rf.done:
+			case <-ctx.Done():
+				return
+			}
+			if rf.err != nil {
+				slog.Warn("failed to warm cache entry", "identifier", id, "error", rf.err)
+				failed.Add(1)
+				return
+			}
+			warmed.Add(1)
+		})
+	}
+	wg.Wait()
+	slog.Info("warmed cache", "warmed", warmed.Load(), "failed", failed.Load(), "duration", time.Since(start))
+}
+
+func (c *Cache) warmBatch(ctx context.Context, bf BatchFetcher, identifiers []string) {
+	start := time.Now()
+	release, err := c.acquireFetch(ctx)
+	if err != nil {
+		slog.Warn("failed to warm cache", "identifiers", len(identifiers), "error", err)
+		return
+	}
+	fetched, err := bf.FetchIssues(ctx, identifiers)
+	release()
+	if err != nil {
+		slog.Warn("failed to warm cache", "identifiers", len(identifiers), "error", err)
+		return
+	}
+	// Misses are left for their first reader to confirm.
+	for id, issue := range fetched {
+		c.put(ctx, id, issue)
+	}
+	slog.Info("warmed cache", "warmed", len(identifiers), "found", len(fetched), "duration", time.Since(start))
+}
+
+// RefreshHot keeps identifiers warm by refetching each one every interval
+// plus a random delay of up to jitter, whether or not anyone reads it, so
+// frequently viewed issues rarely cost a reader a fetch. The jitter spreads
+// the refreshes out instead of hitting Linear with all of them at once. It
+// returns immediately; the refreshes stop when ctx is done.
+func (c *Cache) RefreshHot(ctx context.Context, identifiers []string, interval, jitter time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	for _, id := range identifiers {
+		go c.refreshLoop(ctx, id, interval, jitter)
+	}
+}
+
+func (c *Cache) refreshLoop(ctx context.Context, identifier string, interval, jitter time.Duration) {
+	for {
+		wait := interval
+		if jitter > 0 {
+			wait += rand.N(jitter)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		_, hasStale := c.store.Get(ctx, identifier)
+		rf := c.startRefresh(ctx, identifier, hasStale)
+		select {
+		case <-rf.done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// acquireFetch takes a fetch slot, returning the function that frees it.
+func (c *Cache) acquireFetch(ctx context.Context) (release func(), err error) {
+	slots := c.fetchSlots
+	if slots == nil {
+		return func() {}, nil
+	}
+
+	timer := time.NewTimer(c.fetchWait)
+	defer timer.Stop()
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-timer.C:
+		return nil, ErrBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
 func (c *Cache) Get(ctx context.Context, identifier string) (*linearapi.Issue, error) {
-	c.mu.RLock()
-	e, ok := c.entries[identifier]
-	c.mu.RUnlock()
+	issue, _, err := c.GetWithMeta(ctx, identifier)
+	return issue, err
+}
+
+// GetWithMeta is Get that also reports how the result was served.
+func (c *Cache) GetWithMeta(ctx context.Context, identifier string) (*linearapi.Issue, Meta, error) {
+	issue, meta, err := c.get(ctx, identifier)
+	c.count(meta.Status, 1)
+	return issue, meta, err
+}
+
+// count records n lookups served with status.
+func (c *Cache) count(status Status, n int) {
+	switch status {
+	case Hit:
+		c.hits.Add(int64(n))
+	case Miss:
+		c.misses.Add(int64(n))
+	case Stale:
+		c.stale.Add(int64(n))
+	}
+}
 
-	if ok && time.Since(e.fetchedAt) < c.ttl {
-		return e.issue, nil
+func (c *Cache) get(ctx context.Context, identifier string) (*linearapi.Issue, Meta, error) {
+	e, ok := c.store.Get(ctx, identifier)
+	if ok && c.fresh(e) {
+		return e.Issue, Meta{Hit, e.FetchedAt}, nil
 	}
 
-	issue, err := c.fetcher.FetchIssue(ctx, identifier)
+	if ok && time.Since(e.FetchedAt) < c.staleTTL && !c.tooOld(e) {
+		c.startRefresh(ctx, identifier, true)
+		return e.Issue, Meta{Stale, e.FetchedAt}, nil
+	}
+
+	if ok && c.hedgeDelay > 0 && !c.tooOld(e) {
+		return c.hedge(ctx, identifier, e)
+	}
+
+	rf := c.startRefresh(ctx, identifier, false)
+	select {
+	case <-rf.done:
+		return rf.issue, Meta{Miss, rf.fetchedAt}, rf.err
+	case <-ctx.Done():
+		return nil, Meta{Status: Miss}, ctx.Err()
+	}
+}
+
+// GetMany returns the issues for identifiers that exist, fetching every
+// missing or expired one together when the fetcher supports batching.
+func (c *Cache) GetMany(ctx context.Context, identifiers []string) (map[string]*linearapi.Issue, error) {
+	issues := make(map[string]*linearapi.Issue, len(identifiers))
+	var missing []string
+
+	for _, id := range identifiers {
+		if e, ok := c.store.Get(ctx, id); ok && c.fresh(e) {
+			if e.Issue != nil {
+				issues[id] = e.Issue
+			}
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	c.count(Hit, len(identifiers)-len(missing))
+	if len(missing) == 0 {
+		return issues, nil
+	}
+
+	bf, ok := c.fetcher.(BatchFetcher)
+	if !ok {
+		for _, id := range missing {
+			issue, err := c.Get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			if issue != nil {
+				issues[id] = issue
+			}
+		}
+		return issues, nil
+	}
+
+	c.count(Miss, len(missing))
+	release, err := c.acquireFetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fetched, err := bf.FetchIssues(ctx, missing)
+	release()
 	if err != nil {
 		return nil, err
 	}
+	for _, id := range missing {
+		issue, ok := fetched[id]
+		if !ok {
+			// Only FetchIssue applies every lookup rule (sub-teams, the
+			// by-identifier fallback), so it decides what doesn't exist.
+			if issue, _, err = c.get(ctx, id); err != nil {
+				return nil, err
+			}
+		} else {
+			c.put(ctx, id, issue)
+		}
+		if issue != nil {
+			issues[id] = issue
+		}
+	}
+	return issues, nil
+}
+
+// EntryInfo describes one cached entry. Issue is nil for identifiers that
+// were looked up but don't exist.
+type EntryInfo struct {
+	Identifier string
+	Age        time.Duration
+	Issue      *linearapi.Issue
+}
+
+// Snapshot lists the cached entries, sorted by identifier. It is empty for
+// stores that can't list their entries.
+func (c *Cache) Snapshot() []EntryInfo {
+	l, ok := c.store.(Lister)
+	if !ok {
+		return []EntryInfo{}
+	}
+	entries := l.Entries()
+	infos := make([]EntryInfo, 0, len(entries))
+	for id, e := range entries {
+		infos = append(infos, EntryInfo{
+			Identifier: id,
+			Age:        time.Since(e.FetchedAt),
+			Issue:      e.Issue,
+		})
+	}
+
+	slices.SortFunc(infos, func(a, b EntryInfo) int {
+		return strings.Compare(a.Identifier, b.Identifier)
+	})
+	return infos
+}
 
+func (c *Cache) tooOld(e Entry) bool {
+	return c.maxAge > 0 && time.Since(e.FetchedAt) >= c.maxAge
+}
+
+func (c *Cache) hedge(ctx context.Context, identifier string, stale Entry) (*linearapi.Issue, Meta, error) {
+	rf := c.startRefresh(ctx, identifier, true)
+
+	timer := time.NewTimer(c.hedgeDelay)
+	defer timer.Stop()
+
+	staleMeta := Meta{Stale, stale.FetchedAt}
+	select {
+	case <-rf.done:
+		if rf.err != nil {
+			return stale.Issue, staleMeta, nil
+		}
+		return rf.issue, Meta{Miss, rf.fetchedAt}, nil
+	case <-timer.C:
+		return stale.Issue, staleMeta, nil
+	case <-ctx.Done():
+		return nil, staleMeta, ctx.Err()
+	}
+}
+
+// startRefresh fetches identifier in the background, joining a refresh that
+// is already running for it. The fetch outlives ctx so that callers giving up
+// don't fail the others waiting on it. hasStale reports whether an expired
+// entry remains to fall back on.
+func (c *Cache) startRefresh(ctx context.Context, identifier string, hasStale bool) *refresh {
 	c.mu.Lock()
-	c.entries[identifier] = &entry{
-		issue:     issue,
-		fetchedAt: time.Now(),
+	defer c.mu.Unlock()
+
+	if rf, ok := c.refreshing[identifier]; ok {
+		rf.callers++
+		return rf
 	}
-	c.mu.Unlock()
 
-	return issue, nil
+	rf := &refresh{done: make(chan struct{}), callers: 1}
+	c.refreshing[identifier] = rf
+
+	go func() {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), refreshTimeout)
+		defer cancel()
+
+		rf.issue, rf.err = c.fetch(fetchCtx, identifier)
+		if rf.err == nil {
+			rf.fetchedAt = c.put(fetchCtx, identifier, rf.issue)
+		} else if hasStale {
+			slog.Warn("background refresh failed, keeping stale entry", "identifier", identifier, "error", rf.err)
+		}
+
+		c.mu.Lock()
+		delete(c.refreshing, identifier)
+		c.coalesced.Add(int64(rf.callers - 1))
+		c.mu.Unlock()
+		close(rf.done)
+	}()
+
+	return rf
+}
+
+func (c *Cache) fetch(ctx context.Context, identifier string) (*linearapi.Issue, error) {
+	release, err := c.acquireFetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return c.fetcher.FetchIssue(ctx, identifier)
+}
+
+// put records a successful fetch, returning when it was stored.
+func (c *Cache) put(ctx context.Context, identifier string, issue *linearapi.Issue) time.Time {
+	now := time.Now()
+	c.store.Set(ctx, identifier, Entry{Issue: issue, FetchedAt: now}, c.keep(issue))
+	c.lastSuccess.Store(now.UnixNano())
+	return now
+}
+
+// keep is how long the store needs to hold an entry for issue: until it is
+// too old to serve even as a stale fallback, or for good if there is no such
+// age.
+func (c *Cache) keep(issue *linearapi.Issue) time.Duration {
+	if c.maxAge <= 0 {
+		return 0
+	}
+	return max(c.maxAge, c.ttlFor(issue), c.staleTTL)
+}
+
+// Delete drops identifier's entry, if any, so the next Get fetches it again.
+func (c *Cache) Delete(identifier string) {
+	c.store.Delete(context.Background(), identifier)
+}
+
+// Clear drops every entry.
+func (c *Cache) Clear() {
+	c.store.Clear(context.Background())
 }
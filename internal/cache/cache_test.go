@@ -3,6 +3,10 @@ package cache
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -97,3 +101,725 @@ func TestCacheNilIssue(t *testing.T) {
 		t.Errorf("fetcher called %d times, want 1 (nil should be cached)", fetcher.calls.Load())
 	}
 }
+
+func TestCacheNegativeTTL(t *testing.T) {
+	fetcher := &mockFetcher{}
+	c := New(fetcher, 1*time.Minute)
+	c.SetNegativeTTL(20 * time.Millisecond)
+	ctx := context.Background()
+
+	if got, err := c.Get(ctx, "MIR-999"); err != nil || got != nil {
+		t.Fatalf("Get = %v, %v; want nil, nil", got, err)
+	}
+	if _, err := c.Get(ctx, "MIR-999"); err != nil {
+		t.Fatal(err)
+	}
+	if n := fetcher.calls.Load(); n != 1 {
+		t.Fatalf("fetcher called %d times, want 1 (nil is cached)", n)
+	}
+
+	// Once the negative TTL passes the issue is looked up again, and a
+	// found issue then keeps the longer TTL.
+	time.Sleep(30 * time.Millisecond)
+	fetcher.issue = &linearapi.Issue{Identifier: "MIR-999"}
+	if got, err := c.Get(ctx, "MIR-999"); err != nil || got == nil {
+		t.Fatalf("Get after negative TTL = %v, %v; want the issue", got, err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := c.Get(ctx, "MIR-999"); err != nil {
+		t.Fatal(err)
+	}
+	if n := fetcher.calls.Load(); n != 2 {
+		t.Errorf("fetcher called %d times, want 2", n)
+	}
+}
+
+// issuesFetcher serves fixed issues and counts fetches per identifier.
+type issuesFetcher struct {
+	issues map[string]*linearapi.Issue
+	mu     sync.Mutex
+	calls  map[string]int
+}
+
+func (f *issuesFetcher) FetchIssue(_ context.Context, identifier string) (*linearapi.Issue, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[identifier]++
+	return f.issues[identifier], nil
+}
+
+func TestCacheStateTTLs(t *testing.T) {
+	fetcher := &issuesFetcher{
+		issues: map[string]*linearapi.Issue{
+			"MIR-1": {Identifier: "MIR-1", State: linearapi.State{Type: "completed"}},
+			"MIR-2": {Identifier: "MIR-2", State: linearapi.State{Type: "started"}},
+			"MIR-3": {Identifier: "MIR-3", State: linearapi.State{Type: "backlog"}},
+		},
+		calls: map[string]int{},
+	}
+	c := New(fetcher, 200*time.Millisecond)
+	c.SetStateTTLs(map[string]time.Duration{
+		"completed": time.Hour,
+		"started":   20 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	for _, id := range []string{"MIR-1", "MIR-2", "MIR-3"} {
+		if _, err := c.Get(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(30 * time.Millisecond)
+	for _, id := range []string{"MIR-1", "MIR-2", "MIR-3"} {
+		if _, err := c.Get(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The started issue outlived its short TTL; the completed one and the
+	// unmapped backlog one, on the default TTL, are still fresh.
+	want := map[string]int{"MIR-1": 1, "MIR-2": 2, "MIR-3": 1}
+	fetcher.mu.Lock()
+	defer fetcher.mu.Unlock()
+	if !maps.Equal(fetcher.calls, want) {
+		t.Errorf("fetches = %v, want %v", fetcher.calls, want)
+	}
+}
+
+func TestCacheDelete(t *testing.T) {
+	fetcher := &mockFetcher{issue: &linearapi.Issue{Identifier: "MIR-1"}}
+	c := New(fetcher, 1*time.Minute)
+	ctx := context.Background()
+
+	if _, err := c.Get(ctx, "MIR-1"); err != nil {
+		t.Fatal(err)
+	}
+	c.Delete("MIR-1")
+	c.Delete("MIR-2") // not cached
+	if _, err := c.Get(ctx, "MIR-1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := fetcher.calls.Load(); n != 2 {
+		t.Errorf("fetcher called %d times, want 2 (deleted entry refetched)", n)
+	}
+}
+
+func TestCacheClear(t *testing.T) {
+	fetcher := &mockFetcher{issue: &linearapi.Issue{Identifier: "MIR-1"}}
+	c := New(fetcher, 1*time.Minute)
+	c.SetMaxEntries(2)
+	ctx := context.Background()
+
+	for _, id := range []string{"MIR-1", "MIR-2"} {
+		if _, err := c.Get(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Clear()
+	if n := len(c.Snapshot()); n != 0 {
+		t.Fatalf("%d entries after Clear, want 0", n)
+	}
+	for _, id := range []string{"MIR-1", "MIR-2", "MIR-3"} {
+		if _, err := c.Get(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := fetcher.calls.Load(); n != 5 {
+		t.Errorf("fetcher called %d times, want 5 (cleared entries refetched)", n)
+	}
+	if n := len(c.Snapshot()); n != 2 {
+		t.Errorf("%d entries cached, want the limit of 2", n)
+	}
+}
+
+type sequenceFetcher struct {
+	delays []time.Duration
+	calls  atomic.Int32
+}
+
+func (s *sequenceFetcher) FetchIssue(ctx context.Context, identifier string) (*linearapi.Issue, error) {
+	n := int(s.calls.Add(1))
+	if n <= len(s.delays) {
+		select {
+		case <-time.After(s.delays[n-1]):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return &linearapi.Issue{Identifier: identifier, Title: fmt.Sprintf("v%d", n)}, nil
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	fetcher := &sequenceFetcher{delays: []time.Duration{0, 100 * time.Millisecond}}
+	c := New(fetcher, 1*time.Millisecond)
+	c.SetStaleTTL(time.Minute)
+	ctx := context.Background()
+
+	if _, err := c.Get(ctx, "MIR-1"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// Expired reads return the old issue at once, sharing one refresh.
+	start := time.Now()
+	for range 3 {
+		got, meta, err := c.GetWithMeta(ctx, "MIR-1")
+		if err != nil {
+			t.Fatalf("Get (stale): %v", err)
+		}
+		if got.Title != "v1" || meta.Status != Stale {
+			t.Errorf("got %q (%s), want stale v1", got.Title, meta.Status)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("stale reads took %v, want no wait for the refresh", elapsed)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	// Snapshot reads the refreshed entry without starting another refresh.
+	if snap := c.Snapshot(); len(snap) != 1 || snap[0].Issue.Title != "v2" {
+		t.Errorf("cached entry = %+v, want refreshed v2", snap)
+	}
+	if n := fetcher.calls.Load(); n != 2 {
+		t.Errorf("fetcher called %d times, want 2", n)
+	}
+}
+
+func TestCacheStaleWhileRevalidateKeepsStaleOnError(t *testing.T) {
+	fetcher := &mockFetcher{issue: &linearapi.Issue{Identifier: "MIR-1"}}
+	c := New(fetcher, 1*time.Millisecond)
+	c.SetStaleTTL(time.Minute)
+	ctx := context.Background()
+
+	if _, err := c.Get(ctx, "MIR-1"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	fetcher.err = errors.New("linear down")
+
+	for range 2 {
+		got, meta, err := c.GetWithMeta(ctx, "MIR-1")
+		if err != nil || got == nil || meta.Status != Stale {
+			t.Fatalf("GetWithMeta = %v, %s, %v; want the stale issue", got, meta.Status, err)
+		}
+		time.Sleep(20 * time.Millisecond) // let the refresh fail
+	}
+}
+
+func TestCacheHedgeFastRefresh(t *testing.T) {
+	fetcher := &sequenceFetcher{}
+	c := New(fetcher, 1*time.Millisecond)
+	c.SetHedgeDelay(time.Second)
+
+	if _, err := c.Get(context.Background(), "MIR-1"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	got, err := c.Get(context.Background(), "MIR-1")
+	if err != nil {
+		t.Fatalf("Get (hedged): %v", err)
+	}
+	if got.Title != "v2" {
+		t.Errorf("Title = %q, want fresh %q", got.Title, "v2")
+	}
+}
+
+func TestCacheHedgeSlowRefresh(t *testing.T) {
+	fetcher := &sequenceFetcher{delays: []time.Duration{0, 500 * time.Millisecond}}
+	c := New(fetcher, 1*time.Millisecond)
+	c.SetHedgeDelay(10 * time.Millisecond)
+
+	if _, err := c.Get(context.Background(), "MIR-1"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	start := time.Now()
+	got, meta, err := c.GetWithMeta(context.Background(), "MIR-1")
+	if err != nil {
+		t.Fatalf("Get (hedged): %v", err)
+	}
+	if got.Title != "v1" {
+		t.Errorf("Title = %q, want stale %q", got.Title, "v1")
+	}
+	if meta.Status != Stale {
+		t.Errorf("status = %q, want %q", meta.Status, Stale)
+	}
+	if age := time.Since(meta.FetchedAt); age < 5*time.Millisecond {
+		t.Errorf("stale entry age = %v, want at least 5ms", age)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("hedged Get took %v, should not wait for the slow refresh", elapsed)
+	}
+}
+
+func TestCacheHedgeFailedRefreshServesStale(t *testing.T) {
+	fetcher := &mockFetcher{issue: &linearapi.Issue{Identifier: "MIR-1"}}
+	c := New(fetcher, 1*time.Millisecond)
+	c.SetHedgeDelay(time.Second)
+
+	if _, err := c.Get(context.Background(), "MIR-1"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	fetcher.err = errors.New("linear down")
+
+	got, err := c.Get(context.Background(), "MIR-1")
+	if err != nil {
+		t.Fatalf("Get (stale): %v", err)
+	}
+	if got == nil || got.Identifier != "MIR-1" {
+		t.Errorf("got %+v, want stale MIR-1", got)
+	}
+}
+
+func TestCacheMaxAgeBypassesStaleFallback(t *testing.T) {
+	fetcher := &mockFetcher{issue: &linearapi.Issue{Identifier: "MIR-1"}}
+	c := New(fetcher, 1*time.Millisecond)
+	c.SetHedgeDelay(time.Second)
+	c.SetMaxAge(5 * time.Millisecond)
+
+	if _, err := c.Get(context.Background(), "MIR-1"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	fetcher.err = errors.New("linear down")
+
+	got, err := c.Get(context.Background(), "MIR-1")
+	if err == nil {
+		t.Fatalf("expected fetch error for entry older than max age, got %+v", got)
+	}
+	if fetcher.calls.Load() != 2 {
+		t.Errorf("fetcher called %d times, want 2", fetcher.calls.Load())
+	}
+}
+
+type batchFetcher struct {
+	mockFetcher
+	issues  map[string]*linearapi.Issue
+	batches [][]string
+}
+
+func (b *batchFetcher) FetchIssues(_ context.Context, identifiers []string) (map[string]*linearapi.Issue, error) {
+	b.batches = append(b.batches, identifiers)
+	found := make(map[string]*linearapi.Issue)
+	for _, id := range identifiers {
+		if issue, ok := b.issues[id]; ok {
+			found[id] = issue
+		}
+	}
+	return found, nil
+}
+
+func TestCacheGetManyBatchesMisses(t *testing.T) {
+	fetcher := &batchFetcher{issues: map[string]*linearapi.Issue{
+		"MIR-1": {Identifier: "MIR-1"},
+		"MIR-2": {Identifier: "MIR-2"},
+	}}
+	c := New(fetcher, 1*time.Minute)
+
+	got, err := c.GetMany(context.Background(), []string{"MIR-1", "MIR-2", "MIR-3"})
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if len(got) != 2 || got["MIR-1"] == nil || got["MIR-2"] == nil {
+		t.Errorf("GetMany = %v, want MIR-1 and MIR-2", got)
+	}
+	if len(fetcher.batches) != 1 {
+		t.Fatalf("FetchIssues called %d times, want 1", len(fetcher.batches))
+	}
+
+	if _, err := c.GetMany(context.Background(), []string{"MIR-1", "MIR-3"}); err != nil {
+		t.Fatalf("GetMany (cached): %v", err)
+	}
+	if len(fetcher.batches) != 1 {
+		t.Errorf("FetchIssues called %d times, want 1 (hits and misses should be cached)", len(fetcher.batches))
+	}
+	if n := fetcher.calls.Load(); n != 1 {
+		t.Errorf("FetchIssue called %d times, want 1 to confirm MIR-3 is missing", n)
+	}
+}
+
+func TestCacheGetManyConfirmsBatchMisses(t *testing.T) {
+	// The batch misses MIR-7, which FetchIssue finds, as with an issue
+	// that moved to a sub-team.
+	fetcher := &batchFetcher{
+		mockFetcher: mockFetcher{issue: &linearapi.Issue{Identifier: "MIR-7"}},
+		issues:      map[string]*linearapi.Issue{"MIR-1": {Identifier: "MIR-1"}},
+	}
+	c := New(fetcher, time.Minute)
+
+	got, err := c.GetMany(context.Background(), []string{"MIR-1", "MIR-7"})
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if got["MIR-7"] == nil {
+		t.Errorf("GetMany = %v, want MIR-7 found by FetchIssue", got)
+	}
+	issue, err := c.Get(context.Background(), "MIR-7")
+	if err != nil || issue == nil {
+		t.Errorf("Get(MIR-7) = %v, %v; want the issue, not a cached miss", issue, err)
+	}
+	if n := fetcher.calls.Load(); n != 1 {
+		t.Errorf("FetchIssue called %d times, want 1", n)
+	}
+
+	c.Warm(context.Background(), []string{"MIR-1", "MIR-8"})
+	fetcher.issue = &linearapi.Issue{Identifier: "MIR-8"}
+	if issue, err := c.Get(context.Background(), "MIR-8"); err != nil || issue == nil {
+		t.Errorf("Get(MIR-8) after Warm = %v, %v; want the issue, not a cached miss", issue, err)
+	}
+}
+
+func TestCacheSnapshot(t *testing.T) {
+	fetcher := &batchFetcher{issues: map[string]*linearapi.Issue{
+		"MIR-2": {Identifier: "MIR-2"},
+	}}
+	c := New(fetcher, 1*time.Minute)
+
+	if _, err := c.GetMany(context.Background(), []string{"MIR-2", "MIR-1"}); err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+
+	snap := c.Snapshot()
+	if len(snap) != 2 {
+		t.Fatalf("Snapshot has %d entries, want 2", len(snap))
+	}
+	if snap[0].Identifier != "MIR-1" || snap[0].Issue != nil {
+		t.Errorf("snap[0] = %+v, want missing MIR-1", snap[0])
+	}
+	if snap[1].Identifier != "MIR-2" || snap[1].Issue == nil {
+		t.Errorf("snap[1] = %+v, want cached MIR-2", snap[1])
+	}
+	for _, e := range snap {
+		if e.Age < 0 || e.Age > time.Minute {
+			t.Errorf("%s age = %v, want recent", e.Identifier, e.Age)
+		}
+	}
+}
+
+type blockingFetcher struct {
+	release chan struct{}
+	err     error
+	calls   atomic.Int32
+}
+
+func (b *blockingFetcher) FetchIssue(ctx context.Context, identifier string) (*linearapi.Issue, error) {
+	b.calls.Add(1)
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &linearapi.Issue{Identifier: identifier}, nil
+}
+
+func TestCacheCoalescesConcurrentMisses(t *testing.T) {
+	fetcher := &blockingFetcher{release: make(chan struct{})}
+	c := New(fetcher, 1*time.Minute)
+
+	const callers = 5
+	errs := make(chan error, callers)
+	for range callers {
+		go func() {
+			_, err := c.Get(context.Background(), "MIR-1")
+			errs <- err
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(fetcher.release)
+	for range callers {
+		if err := <-errs; err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+	}
+
+	if n := fetcher.calls.Load(); n != 1 {
+		t.Errorf("fetcher called %d times, want 1", n)
+	}
+	if got := c.Stats().Coalesced; got != callers-1 {
+		t.Errorf("Stats().Coalesced = %d, want %d", got, callers-1)
+	}
+}
+
+func TestCacheCoalescedErrorNotCached(t *testing.T) {
+	fetcher := &blockingFetcher{release: make(chan struct{}), err: errors.New("throttled")}
+	c := New(fetcher, 1*time.Minute)
+
+	const callers = 5
+	errs := make(chan error, callers)
+	for range callers {
+		go func() {
+			_, err := c.Get(context.Background(), "MIR-1")
+			errs <- err
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(fetcher.release)
+	for range callers {
+		if err := <-errs; !errors.Is(err, fetcher.err) {
+			t.Errorf("Get error = %v, want %v", err, fetcher.err)
+		}
+	}
+	if n := fetcher.calls.Load(); n != 1 {
+		t.Errorf("fetcher called %d times, want 1", n)
+	}
+
+	// The failure wasn't cached, so the next Get tries again.
+	fetcher.err = nil
+	if _, err := c.Get(context.Background(), "MIR-1"); err != nil {
+		t.Fatalf("Get after failure: %v", err)
+	}
+	if n := fetcher.calls.Load(); n != 2 {
+		t.Errorf("fetcher called %d times, want 2", n)
+	}
+}
+
+func TestCacheCanceledWaiterKeepsSharedFetch(t *testing.T) {
+	fetcher := &blockingFetcher{release: make(chan struct{})}
+	c := New(fetcher, 1*time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.Get(ctx, "MIR-1")
+		first <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	second := make(chan error, 1)
+	go func() {
+		_, err := c.Get(context.Background(), "MIR-1")
+		second <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// The caller that started the fetch gives up; the other still gets the
+	// result.
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled Get error = %v, want context.Canceled", err)
+	}
+	close(fetcher.release)
+	if err := <-second; err != nil {
+		t.Errorf("other Get error = %v, want nil", err)
+	}
+	if n := fetcher.calls.Load(); n != 1 {
+		t.Errorf("fetcher called %d times, want 1", n)
+	}
+}
+
+func TestCacheMaxEntriesEvictsLeastRecentlyUsed(t *testing.T) {
+	fetcher := &sequenceFetcher{}
+	c := New(fetcher, 1*time.Minute)
+	c.SetMaxEntries(3)
+	ctx := context.Background()
+
+	for _, id := range []string{"MIR-1", "MIR-2", "MIR-3"} {
+		if _, err := c.Get(ctx, id); err != nil {
+			t.Fatalf("Get %s: %v", id, err)
+		}
+	}
+	// Reading MIR-1 makes MIR-2 the least recently used.
+	if _, err := c.Get(ctx, "MIR-1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"MIR-4", "MIR-5"} {
+		if _, err := c.Get(ctx, id); err != nil {
+			t.Fatalf("Get %s: %v", id, err)
+		}
+	}
+
+	var got []string
+	for _, e := range c.Snapshot() {
+		got = append(got, e.Identifier)
+	}
+	if want := []string{"MIR-1", "MIR-4", "MIR-5"}; !slices.Equal(got, want) {
+		t.Errorf("cached = %v, want %v", got, want)
+	}
+
+	// Evicted entries are fetched again; kept ones aren't.
+	calls := fetcher.calls.Load()
+	if _, err := c.Get(ctx, "MIR-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "MIR-2"); err != nil {
+		t.Fatal(err)
+	}
+	if n := fetcher.calls.Load() - calls; n != 1 {
+		t.Errorf("fetched %d times after eviction, want 1 (only MIR-2)", n)
+	}
+
+	c.SetMaxEntries(1)
+	if n := len(c.Snapshot()); n != 1 {
+		t.Errorf("after lowering the limit, %d entries cached, want 1", n)
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	fetcher := &mockFetcher{issue: &linearapi.Issue{Identifier: "MIR-1"}}
+	c := New(fetcher, 1*time.Minute)
+	c.SetMaxEntries(2)
+	ctx := context.Background()
+
+	for _, id := range []string{"MIR-1", "MIR-1", "MIR-2", "MIR-1", "MIR-3"} {
+		if _, err := c.Get(ctx, id); err != nil {
+			t.Fatalf("Get %s: %v", id, err)
+		}
+	}
+
+	got := c.Stats()
+	got.LastSuccess = time.Time{}
+	want := Stats{Hits: 2, Misses: 3, Entries: 2, Evictions: 1}
+	if got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCacheLastSuccess(t *testing.T) {
+	fetcher := &mockFetcher{issue: &linearapi.Issue{Identifier: "MIR-1"}}
+	c := New(fetcher, 0)
+
+	if got := c.Stats().LastSuccess; !got.IsZero() {
+		t.Fatalf("LastSuccess before any fetch = %v, want zero", got)
+	}
+
+	before := time.Now()
+	if _, err := c.Get(context.Background(), "MIR-1"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	success := c.Stats().LastSuccess
+	if success.Before(before) {
+		t.Fatalf("LastSuccess = %v, want at or after %v", success, before)
+	}
+
+	fetcher.err = errors.New("network error")
+	c.SetMaxAge(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, err := c.Get(context.Background(), "MIR-1"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if got := c.Stats().LastSuccess; !got.Equal(success) {
+		t.Errorf("LastSuccess after failure = %v, want unchanged %v", got, success)
+	}
+}
+
+// concurrencyFetcher records the most fetches it saw at once and fails for
+// identifiers in fail.
+type concurrencyFetcher struct {
+	fail     string
+	inFlight atomic.Int32
+	max      atomic.Int32
+	calls    atomic.Int32
+}
+
+func (f *concurrencyFetcher) FetchIssue(_ context.Context, identifier string) (*linearapi.Issue, error) {
+	f.calls.Add(1)
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for {
+		m := f.max.Load()
+		if n <= m || f.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	if identifier == f.fail {
+		return nil, errors.New("boom")
+	}
+	return &linearapi.Issue{Identifier: identifier}, nil
+}
+
+func TestCacheWarm(t *testing.T) {
+	fetcher := &concurrencyFetcher{fail: "MIR-3"}
+	c := New(fetcher, time.Minute)
+	if _, err := c.Get(context.Background(), "MIR-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for i := 1; i <= 3*warmConcurrency; i++ {
+		ids = append(ids, fmt.Sprintf("MIR-%d", i))
+	}
+	c.Warm(context.Background(), ids)
+
+	if got, want := fetcher.calls.Load(), int32(len(ids)); got != want {
+		t.Errorf("fetches = %d, want %d (MIR-1 was already cached)", got, want)
+	}
+	if m := fetcher.max.Load(); m > warmConcurrency {
+		t.Errorf("max concurrent fetches = %d, want at most %d", m, warmConcurrency)
+	}
+	if got, want := len(c.Snapshot()), len(ids)-1; got != want {
+		t.Errorf("Snapshot has %d entries, want %d (all but the failed MIR-3)", got, want)
+	}
+	if s := c.Stats(); s.Misses != 1 {
+		t.Errorf("Misses = %d, want 1; warming shouldn't count as lookups", s.Misses)
+	}
+}
+
+func TestCacheWarmBatches(t *testing.T) {
+	fetcher := &batchFetcher{issues: map[string]*linearapi.Issue{
+		"MIR-1": {Identifier: "MIR-1"},
+		"MIR-2": {Identifier: "MIR-2"},
+	}}
+	c := New(fetcher, time.Minute)
+
+	c.Warm(context.Background(), []string{"MIR-1", "MIR-2", "MIR-404"})
+	if len(fetcher.batches) != 1 {
+		t.Fatalf("FetchIssues called %d times, want 1", len(fetcher.batches))
+	}
+	if n := fetcher.calls.Load(); n != 0 {
+		t.Errorf("FetchIssue called %d times, want 0", n)
+	}
+
+	// Found identifiers are cached; missing ones are confirmed by their
+	// first reader.
+	c.Warm(context.Background(), []string{"MIR-1", "MIR-404"})
+	if _, err := c.Get(context.Background(), "MIR-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fetcher.batches) != 2 || fetcher.calls.Load() != 0 {
+		t.Errorf("warmed identifiers were fetched again: %d batches, %d single fetches", len(fetcher.batches), fetcher.calls.Load())
+	}
+	if _, err := c.Get(context.Background(), "MIR-404"); err != nil {
+		t.Fatal(err)
+	}
+	if n := fetcher.calls.Load(); n != 1 {
+		t.Errorf("FetchIssue called %d times, want 1 to confirm MIR-404 is missing", n)
+	}
+}
+
+func TestCacheRefreshHot(t *testing.T) {
+	fetcher := &mockFetcher{issue: &linearapi.Issue{Identifier: "MIR-1"}}
+	c := New(fetcher, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.RefreshHot(ctx, []string{"MIR-1"}, 5*time.Millisecond, 2*time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for fetcher.calls.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("fetcher called %d times, want at least 3", fetcher.calls.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if len(c.Snapshot()) != 1 {
+		t.Errorf("Snapshot has %d entries, want 1", len(c.Snapshot()))
+	}
+
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	calls := fetcher.calls.Load()
+	time.Sleep(20 * time.Millisecond)
+	if got := fetcher.calls.Load(); got != calls {
+		t.Errorf("fetcher called %d times after cancel, want %d", got, calls)
+	}
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+)
+
+// DefaultListTTL is how long a PublicList reuses its last query.
+const DefaultListTTL = time.Minute
+
+// ListFetcher lists a team's public issues, most recently updated first.
+type ListFetcher interface {
+	FetchPublicIssues(ctx context.Context, teamKey string, gate linearapi.GateMode, first int) ([]*linearapi.Issue, error)
+}
+
+// PublicList caches the list of a team's public issues so every listing
+// (the feed, a sitemap, an export) shares one query to Linear. A crawler
+// fetching several of them at once costs a single list query per TTL.
+type PublicList struct {
+	fetcher ListFetcher
+	teamKey string
+	gate    linearapi.GateMode
+	size    int
+	ttl     time.Duration
+
+	mu        sync.Mutex
+	issues    []*linearapi.Issue
+	fetchedAt time.Time
+	pending   *listRefresh // the fetch in flight, if any
+}
+
+// listRefresh is a fetch of the list that every caller arriving while it
+// runs waits on, instead of starting its own.
+type listRefresh struct {
+	done   chan struct{}
+	issues []*linearapi.Issue
+	err    error
+}
+
+// NewPublicList lists up to size of teamKey's issues that are public under
+// gate, refetching at most once per ttl.
+func NewPublicList(fetcher ListFetcher, teamKey string, gate linearapi.GateMode, size int, ttl time.Duration) *PublicList {
+	return &PublicList{
+		fetcher: fetcher,
+		teamKey: teamKey,
+		gate:    gate,
+		size:    size,
+		ttl:     ttl,
+	}
+}
+
+// Issues returns the public issues, most recently updated first. Callers
+// must not modify the returned slice.
+func (l *PublicList) Issues(ctx context.Context) ([]*linearapi.Issue, error) {
+	l.mu.Lock()
+	if !l.fetchedAt.IsZero() && time.Since(l.fetchedAt) < l.ttl {
+		issues := l.issues
+		l.mu.Unlock()
+		return issues, nil
+	}
+	rf := l.pending
+	if rf == nil {
+		rf = l.startRefresh(ctx)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-rf.done:
+		return rf.issues, rf.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startRefresh fetches the list in the background. The fetch outlives ctx so
+// that callers giving up don't fail the others waiting on it. l.mu must be
+// held.
+func (l *PublicList) startRefresh(ctx context.Context) *listRefresh {
+	rf := &listRefresh{done: make(chan struct{})}
+	l.pending = rf
+
+	go func() {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), refreshTimeout)
+		defer cancel()
+
+		rf.issues, rf.err = l.fetcher.FetchPublicIssues(fetchCtx, l.teamKey, l.gate, l.size)
+
+		l.mu.Lock()
+		if rf.err == nil {
+			l.issues, l.fetchedAt = rf.issues, time.Now()
+		}
+		l.pending = nil
+		l.mu.Unlock()
+		close(rf.done)
+	}()
+
+	return rf
+}
+
+// Identifiers returns the identifiers of the public issues, in the same
+// order as Issues.
+func (l *PublicList) Identifiers(ctx context.Context) ([]string, error) {
+	issues, err := l.Issues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(issues))
+	for i, issue := range issues {
+		ids[i] = issue.Identifier
+	}
+	return ids, nil
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+)
+
+type mockLister struct {
+	calls atomic.Int32
+}
+
+func (m *mockLister) FetchPublicIssues(_ context.Context, teamKey string, _ linearapi.GateMode, first int) ([]*linearapi.Issue, error) {
+	m.calls.Add(1)
+	time.Sleep(5 * time.Millisecond)
+	return []*linearapi.Issue{{Identifier: teamKey + "-2"}, {Identifier: teamKey + "-1"}}[:first], nil
+}
+
+func TestPublicListSharesQuery(t *testing.T) {
+	lister := &mockLister{}
+	list := NewPublicList(lister, "MIR", linearapi.GateAllowlist, 2, time.Minute)
+
+	// A feed and a sitemap requested together.
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := list.Issues(context.Background()); err != nil {
+				t.Errorf("Issues: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	ids, err := list.Identifiers(context.Background())
+	if err != nil {
+		t.Fatalf("Identifiers: %v", err)
+	}
+	if want := []string{"MIR-2", "MIR-1"}; !slices.Equal(ids, want) {
+		t.Errorf("Identifiers = %v, want %v", ids, want)
+	}
+	if n := lister.calls.Load(); n != 1 {
+		t.Errorf("list queried %d times, want 1", n)
+	}
+}
+
+func TestPublicListExpiry(t *testing.T) {
+	lister := &mockLister{}
+	list := NewPublicList(lister, "MIR", linearapi.GateAllowlist, 1, time.Millisecond)
+
+	if _, err := list.Issues(context.Background()); err != nil {
+		t.Fatalf("Issues: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := list.Issues(context.Background()); err != nil {
+		t.Fatalf("Issues: %v", err)
+	}
+	if n := lister.calls.Load(); n != 2 {
+		t.Errorf("list queried %d times, want 2", n)
+	}
+}
+
+type blockingLister struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (b *blockingLister) FetchPublicIssues(context.Context, string, linearapi.GateMode, int) ([]*linearapi.Issue, error) {
+	b.calls.Add(1)
+	<-b.release
+	return []*linearapi.Issue{{Identifier: "MIR-1"}}, nil
+}
+
+func TestPublicListWaiterHonorsContext(t *testing.T) {
+	lister := &blockingLister{release: make(chan struct{})}
+	list := NewPublicList(lister, "MIR", linearapi.GateAllowlist, 1, time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := list.Issues(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Issues err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The fetch outlives the caller that gave up; a later caller joins it.
+	done := make(chan error, 1)
+	go func() {
+		_, err := list.Issues(context.Background())
+		done <- err
+	}()
+	close(lister.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Issues: %v", err)
+	}
+	if _, err := list.Issues(context.Background()); err != nil {
+		t.Fatalf("Issues: %v", err)
+	}
+	if n := lister.calls.Load(); n != 1 {
+		t.Errorf("list queried %d times, want 1", n)
+	}
+}
@@ -0,0 +1,409 @@
+// Package redisstore keeps cache entries in Redis, so that several replicas
+// of the bridge share one cache and one fetch from Linear serves them all.
+package redisstore
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/cache"
+)
+
+// DefaultPrefix namespaces the bridge's keys within the Redis database.
+const DefaultPrefix = "linear-issue-bridge:issue:"
+
+const (
+	dialTimeout = 5 * time.Second
+	opTimeout   = 2 * time.Second
+	maxConns    = 8
+
+	// After a connection failure the store skips Redis for minBackoff,
+	// doubling up to maxBackoff while the failures continue.
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// errUnavailable is returned without touching the network while the store
+// is backing off after a failure.
+var errUnavailable = errors.New("redis unavailable, backing off")
+
+// Store is a cache.Store backed by Redis. Entries are JSON and expire through
+// Redis's own TTLs. Redis errors are treated as misses, so an unreachable
+// server costs fetches from Linear but doesn't fail reads. After a connection
+// failure the store stops calling Redis for a while, so reads degrade to
+// misses at once instead of each waiting out a dial.
+type Store struct {
+	addr     string
+	username string
+	password string
+	db       int
+	tls      bool
+	prefix   string
+
+	open chan struct{} // one token per open connection, bounding them
+	idle chan *conn
+
+	mu       sync.Mutex
+	failures int // consecutive connection failures
+	retryAt  time.Time
+	probing  bool // an operation is testing whether Redis is back
+}
+
+// New connects lazily to the server at rawURL, of the form
+// redis://[[user]:password@]host[:port][/db], or rediss:// for TLS.
+func New(rawURL string) (*Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("redis URL scheme %q, want redis or rediss", u.Scheme)
+	}
+	s := &Store{
+		addr:   u.Host,
+		tls:    u.Scheme == "rediss",
+		prefix: DefaultPrefix,
+		open:   make(chan struct{}, maxConns),
+		idle:   make(chan *conn, maxConns),
+	}
+	if u.Port() == "" {
+		s.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		s.username = u.User.Username()
+		s.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if s.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("redis URL database %q: %w", db, err)
+		}
+	}
+	return s, nil
+}
+
+// SetPrefix changes the prefix of the store's keys, which Clear also limits
+// itself to.
+func (s *Store) SetPrefix(prefix string) {
+	s.prefix = prefix
+}
+
+// warn logs a failed operation, unless it failed because the store is
+// backing off, which was logged when it started, or because ctx ended.
+func warn(ctx context.Context, msg string, err error, args ...any) {
+	if errors.Is(err, errUnavailable) || ended(ctx) {
+		return
+	}
+	slog.Warn(msg, append(args, "error", err)...)
+}
+
+func (s *Store) Get(ctx context.Context, identifier string) (cache.Entry, bool) {
+	reply, err := s.do(ctx, "GET", s.prefix+identifier)
+	if err != nil {
+		warn(ctx, "redis get failed", err, "identifier", identifier)
+		return cache.Entry{}, false
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return cache.Entry{}, false
+	}
+	var e cache.Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		slog.Warn("discarding undecodable redis entry", "identifier", identifier, "error", err)
+		return cache.Entry{}, false
+	}
+	return e, true
+}
+
+func (s *Store) Set(ctx context.Context, identifier string, e cache.Entry, keep time.Duration) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		slog.Warn("failed to encode cache entry", "identifier", identifier, "error", err)
+		return
+	}
+	args := []string{"SET", s.prefix + identifier, string(data)}
+	if keep > 0 {
+		args = append(args, "PX", strconv.FormatInt(keep.Milliseconds(), 10))
+	}
+	if _, err := s.do(ctx, args...); err != nil {
+		warn(ctx, "redis set failed", err, "identifier", identifier)
+	}
+}
+
+func (s *Store) Delete(ctx context.Context, identifier string) {
+	if _, err := s.do(ctx, "DEL", s.prefix+identifier); err != nil {
+		warn(ctx, "redis delete failed", err, "identifier", identifier)
+	}
+}
+
+// Clear deletes every key with the store's prefix.
+func (s *Store) Clear(ctx context.Context) {
+	if err := s.clear(ctx); err != nil {
+		warn(ctx, "redis clear failed", err)
+	}
+}
+
+func (s *Store) clear(ctx context.Context) error {
+	cursor := "0"
+	for {
+		reply, err := s.do(ctx, "SCAN", cursor, "MATCH", s.prefix+"*", "COUNT", "100")
+		if err != nil {
+			return err
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return fmt.Errorf("unexpected SCAN reply %v", reply)
+		}
+		next, _ := page[0].([]byte)
+		keys, _ := page[1].([]any)
+		if len(keys) > 0 {
+			args := []string{"DEL"}
+			for _, k := range keys {
+				if k, ok := k.([]byte); ok {
+					args = append(args, string(k))
+				}
+			}
+			if _, err := s.do(ctx, args...); err != nil {
+				return err
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// do runs one command on a pooled connection.
+func (s *Store) do(ctx context.Context, args ...string) (any, error) {
+	if !s.allow() {
+		return nil, errUnavailable
+	}
+	c, err := s.get(ctx)
+	if err != nil {
+		s.observe(ctx, err)
+		return nil, err
+	}
+	reply, err := c.do(ctx, args...)
+	var re redisError
+	if err != nil && !errors.As(err, &re) {
+		// The connection may be mid-reply; don't reuse it.
+		s.discard(c)
+		s.observe(ctx, err)
+		return nil, err
+	}
+	s.put(c)
+	s.observe(ctx, nil)
+	return reply, err
+}
+
+// allow reports whether an operation may use Redis. While backing off it
+// may not; once the backoff ends, one operation at a time probes the server.
+func (s *Store) allow() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures == 0 {
+		return true
+	}
+	if s.probing || time.Now().Before(s.retryAt) {
+		return false
+	}
+	s.probing = true
+	return true
+}
+
+// observe records how an operation that used Redis went. Failures because
+// ctx ended say nothing about the server and are ignored.
+func (s *Store) observe(ctx context.Context, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.probing = false
+	switch {
+	case err == nil:
+		if s.failures > 0 {
+			slog.Info("redis reachable again")
+		}
+		s.failures = 0
+	case !ended(ctx):
+		s.failures++
+		backoff := min(minBackoff<<min(s.failures-1, 5), maxBackoff)
+		s.retryAt = time.Now().Add(backoff)
+		if s.failures == 1 {
+			slog.Warn("redis unreachable, treating the cache as empty", "error", err, "retry_in", backoff)
+		}
+	}
+}
+
+// ended reports whether ctx is done or past its deadline. I/O bounded by the
+// deadline can time out a moment before ctx itself reports it.
+func ended(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	d, ok := ctx.Deadline()
+	return ok && !time.Now().Before(d)
+}
+
+// get takes an idle connection, or dials one if fewer than maxConns are
+// open, waiting for one of those until ctx is done.
+func (s *Store) get(ctx context.Context) (*conn, error) {
+	select {
+	case c := <-s.idle:
+		return c, nil
+	default:
+	}
+	select {
+	case c := <-s.idle:
+		return c, nil
+	case s.open <- struct{}{}:
+		c, err := s.dial(ctx)
+		if err != nil {
+			<-s.open
+			return nil, err
+		}
+		return c, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// put returns c to the pool. The pool holds every open connection, so it
+// never fills.
+func (s *Store) put(c *conn) {
+	s.idle <- c
+}
+
+// discard closes c, making room for another connection.
+func (s *Store) discard(c *conn) {
+	c.Close()
+	<-s.open
+}
+
+func (s *Store) dial(ctx context.Context) (*conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	var nc net.Conn
+	var err error
+	if s.tls {
+		host, _, _ := net.SplitHostPort(s.addr)
+		d := &tls.Dialer{Config: &tls.Config{ServerName: host}}
+		nc, err = d.DialContext(ctx, "tcp", s.addr)
+	} else {
+		var d net.Dialer
+		nc, err = d.DialContext(ctx, "tcp", s.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	if s.password != "" {
+		args := []string{"AUTH", s.password}
+		if s.username != "" {
+			args = []string{"AUTH", s.username, s.password}
+		}
+		if _, err := c.do(ctx, args...); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("redis auth: %w", err)
+		}
+	}
+	if s.db != 0 {
+		if _, err := c.do(ctx, "SELECT", strconv.Itoa(s.db)); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("redis select %d: %w", s.db, err)
+		}
+	}
+	return c, nil
+}
+
+// redisError is an error reply from the server. The connection stays usable.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// do sends a command and reads its reply, giving up after opTimeout or when
+// ctx is done.
+func (c *conn) do(ctx context.Context, args ...string) (any, error) {
+	deadline := time.Now().Add(opTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	// Interrupt the I/O if ctx is cancelled before the deadline.
+	stop := context.AfterFunc(ctx, func() { c.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return readReply(c.r)
+}
+
+// readReply reads one RESP2 reply: a string for simple strings, int64 for
+// integers, []byte or nil for bulk strings, []any for arrays, and redisError
+// for errors.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty RESP line")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected RESP line %q", line)
+	}
+}
@@ -0,0 +1,334 @@
+package redisstore
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/cache"
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+)
+
+// fakeRedis speaks enough RESP to serve the commands Store sends.
+type fakeRedis struct {
+	t        *testing.T
+	password string
+
+	mu     sync.Mutex
+	values map[string]string
+	ttls   map[string]string // PX argument of the last SET, if any
+	cmds   []string
+}
+
+func startFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeRedis{t: t, password: password, values: map[string]string{}, ttls: map[string]string{}}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+	return f, ln.Addr().String()
+}
+
+func (f *fakeRedis) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	authed := f.password == ""
+	for {
+		req, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, a := range req.([]any) {
+			args = append(args, string(a.([]byte)))
+		}
+		f.mu.Lock()
+		f.cmds = append(f.cmds, args[0])
+		reply := f.exec(args, &authed)
+		f.mu.Unlock()
+		fmt.Fprint(c, reply)
+	}
+}
+
+func (f *fakeRedis) exec(args []string, authed *bool) string {
+	if args[0] == "AUTH" {
+		if args[len(args)-1] != f.password {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		*authed = true
+		return "+OK\r\n"
+	}
+	if !*authed {
+		return "-NOAUTH Authentication required.\r\n"
+	}
+	switch args[0] {
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		v, ok := f.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(v)
+	case "SET":
+		f.values[args[1]] = args[2]
+		f.ttls[args[1]] = ""
+		if len(args) == 5 && args[3] == "PX" {
+			f.ttls[args[1]] = args[4]
+		}
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+		for _, k := range args[1:] {
+			if _, ok := f.values[k]; ok {
+				delete(f.values, k)
+				n++
+			}
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	case "SCAN":
+		prefix := strings.TrimSuffix(args[3], "*")
+		var keys []string
+		for k := range f.values {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, bulk(k))
+			}
+		}
+		return fmt.Sprintf("*2\r\n%s*%d\r\n%s", bulk("0"), len(keys), strings.Join(keys, ""))
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	f, addr := startFakeRedis(t, "secret")
+	s, err := New("redis://:secret@" + addr + "/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.Get(ctx, "MIR-1"); ok {
+		t.Fatal("Get on empty store should miss")
+	}
+
+	fetchedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	issue := &linearapi.Issue{Identifier: "MIR-1", Title: "Shared", Labels: []linearapi.Label{{ID: "l1", Name: "public"}}}
+	s.Set(ctx, "MIR-1", cache.Entry{Issue: issue, FetchedAt: fetchedAt}, 90*time.Second)
+	s.Set(ctx, "MIR-2", cache.Entry{FetchedAt: fetchedAt}, 0)
+
+	got, ok := s.Get(ctx, "MIR-1")
+	if !ok || got.Issue == nil || got.Issue.Title != "Shared" || !got.Issue.HasLabel("public") || !got.FetchedAt.Equal(fetchedAt) {
+		t.Fatalf("Get(MIR-1) = %+v, %t; want the stored issue", got, ok)
+	}
+	if got, ok := s.Get(ctx, "MIR-2"); !ok || got.Issue != nil {
+		t.Errorf("Get(MIR-2) = %+v, %t; want a cached miss", got, ok)
+	}
+
+	f.mu.Lock()
+	if ttl := f.ttls[DefaultPrefix+"MIR-1"]; ttl != "90000" {
+		t.Errorf("MIR-1 PX = %q, want 90000", ttl)
+	}
+	if ttl := f.ttls[DefaultPrefix+"MIR-2"]; ttl != "" {
+		t.Errorf("MIR-2 PX = %q, want none", ttl)
+	}
+	f.values["other:key"] = "kept"
+	f.mu.Unlock()
+
+	s.Delete(ctx, "MIR-1")
+	if _, ok := s.Get(ctx, "MIR-1"); ok {
+		t.Error("Get after Delete should miss")
+	}
+
+	s.Clear(ctx)
+	if _, ok := s.Get(ctx, "MIR-2"); ok {
+		t.Error("Get after Clear should miss")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.values["other:key"]; !ok {
+		t.Error("Clear deleted a key outside the prefix")
+	}
+	if f.cmds[0] != "AUTH" || f.cmds[1] != "SELECT" {
+		t.Errorf("first commands = %v, want AUTH then SELECT", f.cmds[:2])
+	}
+}
+
+func TestStoreUnreachableIsMiss(t *testing.T) {
+	ctx := context.Background()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	s, err := New("redis://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Set(ctx, "MIR-1", cache.Entry{FetchedAt: time.Now()}, 0)
+	if _, ok := s.Get(ctx, "MIR-1"); ok {
+		t.Error("Get from unreachable server should miss")
+	}
+}
+
+// startListener accepts connections on a local port, handing each to
+// handle, and counts them.
+func startListener(t *testing.T, handle func(net.Conn)) (string, *atomic.Int32) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var accepts atomic.Int32
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepts.Add(1)
+			go handle(c)
+		}
+	}()
+	return ln.Addr().String(), &accepts
+}
+
+func TestStoreBacksOffAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	addr, accepts := startListener(t, func(c net.Conn) { c.Close() })
+	s, err := New("redis://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.Get(ctx, "MIR-1"); ok {
+		t.Fatal("Get from a failing server should miss")
+	}
+	for range 5 {
+		s.Get(ctx, "MIR-1")
+		s.Set(ctx, "MIR-1", cache.Entry{FetchedAt: time.Now()}, 0)
+	}
+	if n := accepts.Load(); n != 1 {
+		t.Errorf("connections = %d, want 1; operations during the backoff should skip Redis", n)
+	}
+
+	s.mu.Lock()
+	s.retryAt = time.Now()
+	s.mu.Unlock()
+	s.Get(ctx, "MIR-1")
+	if n := accepts.Load(); n != 2 {
+		t.Errorf("connections = %d, want 2 after the backoff ends", n)
+	}
+}
+
+func TestStoreHonorsContext(t *testing.T) {
+	// The server accepts connections but never replies.
+	addr, _ := startListener(t, func(c net.Conn) { io.Copy(io.Discard, c) })
+	s, err := New("redis://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, ok := s.Get(ctx, "MIR-1"); ok {
+		t.Fatal("Get should miss")
+	}
+	if d := time.Since(start); d >= opTimeout {
+		t.Errorf("Get took %v, want it to return when ctx is done", d)
+	}
+	if !s.allow() {
+		t.Error("a cancelled operation shouldn't make the store back off")
+	}
+}
+
+func TestStoreBoundsConnections(t *testing.T) {
+	addr, accepts := startListener(t, func(c net.Conn) { io.Copy(io.Discard, c) })
+	s, err := New("redis://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	for range 3 * maxConns {
+		wg.Go(func() { s.Get(ctx, "MIR-1") })
+	}
+	wg.Wait()
+	if n := accepts.Load(); n > maxConns {
+		t.Errorf("connections = %d, want at most %d", n, maxConns)
+	}
+}
+
+func TestCacheWithStore(t *testing.T) {
+	_, addr := startFakeRedis(t, "")
+	s, err := New("redis://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher := &countingFetcher{}
+	a := cache.NewWithStore(fetcher, time.Minute, s)
+	b := cache.NewWithStore(fetcher, time.Minute, s)
+
+	if _, err := a.Get(context.Background(), "MIR-1"); err != nil {
+		t.Fatal(err)
+	}
+	got, meta, err := b.GetWithMeta(context.Background(), "MIR-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Identifier != "MIR-1" || meta.Status != cache.Hit {
+		t.Errorf("second replica got %+v, %s; want a hit for MIR-1", got, meta.Status)
+	}
+	if fetcher.calls != 1 {
+		t.Errorf("fetches = %d, want 1 shared between replicas", fetcher.calls)
+	}
+}
+
+type countingFetcher struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *countingFetcher) FetchIssue(_ context.Context, identifier string) (*linearapi.Issue, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return &linearapi.Issue{Identifier: identifier}, nil
+}
+
+func TestNewRejectsBadURLs(t *testing.T) {
+	for _, raw := range []string{"http://localhost:6379", "redis://localhost/db"} {
+		if _, err := New(raw); err == nil {
+			t.Errorf("New(%q) should fail", raw)
+		}
+	}
+}
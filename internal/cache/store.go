@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+)
+
+// Entry is a cached lookup. Issue is nil if no such issue exists.
+type Entry struct {
+	Issue     *linearapi.Issue
+	FetchedAt time.Time
+}
+
+// Store holds a Cache's entries. Replicas can share a Store so that one
+// fetch from Linear serves all of them. Implementations must be safe for
+// concurrent use; a store that can't be reached should behave as empty, and
+// one that does I/O should give up when ctx is done.
+type Store interface {
+	// Get returns identifier's entry, marking it recently used.
+	Get(ctx context.Context, identifier string) (Entry, bool)
+	// Set stores e. The store may drop it after keep; zero means no limit.
+	Set(ctx context.Context, identifier string, e Entry, keep time.Duration)
+	// Delete drops identifier's entry, if any.
+	Delete(ctx context.Context, identifier string)
+	// Clear drops every entry.
+	Clear(ctx context.Context)
+}
+
+// Lister is implemented by stores that can list their entries, which the
+// admin snapshot and entry count need.
+type Lister interface {
+	Entries() map[string]Entry
+}
+
+// MemoryStore is the default Store: a map in this process, bounded by
+// evicting the least recently used entries.
+type MemoryStore struct {
+	mu         sync.Mutex
+	entries    map[string]*memoryEntry
+	lru        *list.List // most recently used first; Value is the identifier
+	maxEntries int
+	evictions  atomic.Int64
+}
+
+type memoryEntry struct {
+	Entry
+	expires time.Time // zero if kept until evicted
+	elem    *list.Element
+}
+
+// NewMemoryStore holds up to maxEntries entries; zero or less means no
+// limit.
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{
+		entries:    make(map[string]*memoryEntry),
+		lru:        list.New(),
+		maxEntries: maxEntries,
+	}
+}
+
+// SetMaxEntries changes the limit, evicting entries if the store is over it.
+func (s *MemoryStore) SetMaxEntries(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxEntries = n
+	s.evict()
+}
+
+// Evictions is how many entries were dropped to stay within the limit.
+func (s *MemoryStore) Evictions() int64 {
+	return s.evictions.Load()
+}
+
+func (s *MemoryStore) Get(_ context.Context, identifier string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[identifier]
+	if !ok {
+		return Entry{}, false
+	}
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		s.remove(identifier, e)
+		return Entry{}, false
+	}
+	s.lru.MoveToFront(e.elem)
+	return e.Entry, true
+}
+
+func (s *MemoryStore) Set(_ context.Context, identifier string, e Entry, keep time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	me := &memoryEntry{Entry: e}
+	if keep > 0 {
+		me.expires = time.Now().Add(keep)
+	}
+	if old, ok := s.entries[identifier]; ok {
+		me.elem = old.elem
+		s.lru.MoveToFront(me.elem)
+	} else {
+		me.elem = s.lru.PushFront(identifier)
+	}
+	s.entries[identifier] = me
+	s.evict()
+}
+
+func (s *MemoryStore) Delete(_ context.Context, identifier string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[identifier]; ok {
+		s.remove(identifier, e)
+	}
+}
+
+func (s *MemoryStore) Clear(context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.entries)
+	s.lru.Init()
+}
+
+func (s *MemoryStore) Entries() map[string]Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make(map[string]Entry, len(s.entries))
+	for id, e := range s.entries {
+		entries[id] = e.Entry
+	}
+	return entries
+}
+
+func (s *MemoryStore) remove(identifier string, e *memoryEntry) {
+	delete(s.entries, identifier)
+	s.lru.Remove(e.elem)
+}
+
+// evict drops least recently used entries until the store is within
+// maxEntries. The caller holds mu.
+func (s *MemoryStore) evict() {
+	if s.maxEntries <= 0 {
+		return
+	}
+	for s.lru.Len() > s.maxEntries {
+		delete(s.entries, s.lru.Remove(s.lru.Back()).(string))
+		s.evictions.Add(1)
+	}
+}
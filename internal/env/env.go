@@ -0,0 +1,132 @@
+// Package env reads configuration from environment variables, so the server
+// and its tools configure Linear clients alike.
+package env
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+	"miren.dev/linear-issue-bridge/internal/retry"
+)
+
+// Linear is how to reach Linear and decide which issues are public.
+type Linear struct {
+	APIKey          string
+	TeamKey         string
+	PublicLabel     string
+	GateMode        linearapi.GateMode
+	IncludeSubTeams bool
+	Retry           retry.Policy
+	LookupFallback  bool
+	FoldLabels      bool
+}
+
+// LoadLinear reads the Linear settings, requiring LINEAR_API_KEY (or
+// LINEAR_API_KEY_FILE) and LINEAR_TEAM_KEY.
+func LoadLinear() (Linear, error) {
+	l := Linear{
+		TeamKey:     os.Getenv("LINEAR_TEAM_KEY"),
+		PublicLabel: os.Getenv("PUBLIC_LABEL"),
+	}
+	var err error
+	if l.APIKey, err = Secret("LINEAR_API_KEY"); err != nil {
+		return l, err
+	}
+	if l.APIKey == "" {
+		return l, fmt.Errorf("LINEAR_API_KEY is required")
+	}
+	if l.TeamKey == "" {
+		return l, fmt.Errorf("LINEAR_TEAM_KEY is required")
+	}
+	if l.PublicLabel == "" {
+		l.PublicLabel = linearapi.DefaultPublicLabel
+	}
+	if l.GateMode, err = linearapi.ParseGateMode(os.Getenv("GATE_MODE")); err != nil {
+		return l, err
+	}
+	if l.IncludeSubTeams, err = Bool("LINEAR_INCLUDE_SUBTEAMS", false); err != nil {
+		return l, err
+	}
+	if l.Retry.MaxRetries, err = Int("LINEAR_MAX_RETRIES", retry.Default.MaxRetries); err != nil {
+		return l, err
+	}
+	if l.Retry.Base, err = Duration("LINEAR_RETRY_BASE", retry.Default.Base); err != nil {
+		return l, err
+	}
+	if l.LookupFallback, err = Bool("LINEAR_LOOKUP_FALLBACK", false); err != nil {
+		return l, err
+	}
+	if l.FoldLabels, err = Bool("LABELS_CASE_INSENSITIVE", false); err != nil {
+		return l, err
+	}
+	return l, nil
+}
+
+// NewClient returns a Linear client configured with these settings.
+func (l Linear) NewClient() *linearapi.Client {
+	client := linearapi.NewClient(l.APIKey)
+	client.SetIncludeSubTeams(l.IncludeSubTeams)
+	client.SetRetryPolicy(l.Retry)
+	client.SetLookupFallback(l.LookupFallback)
+	client.SetCaseInsensitiveLabels(l.FoldLabels)
+	client.SetPublicLabel(l.PublicLabel)
+	return client
+}
+
+// Secret reads a secret from the file named by name_FILE if set, falling
+// back to the name env var itself. Mounted secret files avoid exposing the
+// value in the process environment.
+func Secret(name string) (string, error) {
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return os.Getenv(name), nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read %s_FILE: %w", name, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Bool reads a boolean, returning def if name is unset.
+func Bool(name string, def bool) (bool, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return b, nil
+}
+
+// Int reads an integer, returning def if name is unset.
+func Int(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return n, nil
+}
+
+// Duration reads a duration such as "90s", returning def if name is unset.
+func Duration(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,48 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		env  string
+		file string
+		want string
+	}{
+		{"env only", "from-env", "", "from-env"},
+		{"file only", "", path, "from-file"},
+		{"file wins", "from-env", path, "from-file"},
+		{"neither", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_SECRET", tt.env)
+			t.Setenv("TEST_SECRET_FILE", tt.file)
+
+			got, err := Secret("TEST_SECRET")
+			if err != nil {
+				t.Fatalf("Secret: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Secret = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvSecretMissingFile(t *testing.T) {
+	t.Setenv("TEST_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	if _, err := Secret("TEST_SECRET"); err == nil {
+		t.Error("expected error for unreadable secret file")
+	}
+}
@@ -1,31 +1,47 @@
 package github
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os/exec"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/retry"
 )
 
+// DefaultGitTimeout bounds one run of git log during a backfill.
+const DefaultGitTimeout = 10 * time.Minute
+
 type RepoScanner struct {
-	baseURL string
-	token   string
-	owner   string
-	repo    string
-	gitDir  string
+	baseURL    string
+	token      string
+	owner      string
+	repo       string
+	gitDir     string
+	gitTimeout time.Duration
+	authors    AuthorFilter
+	retry      retry.Policy
+	limiter    *RateLimiter
 }
 
 func NewRepoScanner(token, owner, repo string) *RepoScanner {
 	return &RepoScanner{
-		baseURL: "https://api.github.com",
-		token:   token,
-		owner:   owner,
-		repo:    repo,
+		baseURL:    "https://api.github.com",
+		token:      token,
+		owner:      owner,
+		repo:       repo,
+		gitTimeout: DefaultGitTimeout,
+		retry:      retry.Default,
 	}
 }
 
@@ -33,29 +49,106 @@ func (s *RepoScanner) SetGitDir(dir string) {
 	s.gitDir = dir
 }
 
+// SetGitTimeout bounds each attempt at reading the git log. Zero removes
+// the bound.
+func (s *RepoScanner) SetGitTimeout(d time.Duration) {
+	s.gitTimeout = d
+}
+
+// AuthorFilter picks authors whose pull requests and commits are left out
+// of a scan, such as bots that mention issues only in passing.
+type AuthorFilter struct {
+	Bots   bool     // skip GitHub App logins, which end in "[bot]"
+	Logins []string // skip these logins, compared case-insensitively
+}
+
+// Excludes reports whether work by login should be skipped.
+func (f AuthorFilter) Excludes(login string) bool {
+	if f.Bots && strings.HasSuffix(login, "[bot]") {
+		return true
+	}
+	return slices.ContainsFunc(f.Logins, func(l string) bool {
+		return strings.EqualFold(l, login)
+	})
+}
+
+// SetAuthorFilter skips pull requests and commits by the authors f
+// excludes. Commits are matched on the author name git records, which for
+// GitHub bots is their login.
+func (s *RepoScanner) SetAuthorFilter(f AuthorFilter) {
+	s.authors = f
+}
+
+// SetRateLimiter makes every GitHub request wait on l, which may be shared
+// with other scanners.
+func (s *RepoScanner) SetRateLimiter(l *RateLimiter) {
+	s.limiter = l
+}
+
+// SetRetryPolicy controls how page requests that fail with a network error,
+// rate limit, or server error are retried.
+func (s *RepoScanner) SetRetryPolicy(p retry.Policy) {
+	s.retry = p
+}
+
+// ScanRepo returns every identifier of teamKey's issues referenced in the
+// repo, in the order they were found.
 func (s *RepoScanner) ScanRepo(ctx context.Context, teamKey string) ([]string, error) {
-	prefix := strings.ToUpper(teamKey) + "-"
-	seen := make(map[string]bool)
 	var result []string
+	err := s.ScanRepoFunc(ctx, teamKey, func(id string) error {
+		result = append(result, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ScanRepoFunc calls fn with each identifier of teamKey's issues the first
+// time it is found, while the scan is still running, so callers can act on
+// identifiers without waiting for a large repo to finish or holding them all.
+// An error from fn stops the scan and is returned.
+func (s *RepoScanner) ScanRepoFunc(ctx context.Context, teamKey string, fn func(identifier string) error) error {
+	pattern := teamIssuePattern(teamKey)
+	seen := make(map[string]bool)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
 
 	collect := func(text string) {
-		for _, id := range ScanIdentifiers(text) {
-			if strings.HasPrefix(id, prefix) && !seen[id] {
-				seen[id] = true
-				result = append(result, id)
+		if ctx.Err() != nil {
+			return
+		}
+		for _, id := range scanUnique(pattern, text) {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if err := fn(id); err != nil {
+				cancel(err)
+				return
 			}
 		}
 	}
 
+	// stopped prefers fn's error over the cancellation it caused.
+	stopped := func(err error) error {
+		if cause := context.Cause(ctx); cause != nil && cause != ctx.Err() {
+			return cause
+		}
+		return err
+	}
+
 	before := 0
 
 	if s.gitDir != "" {
 		slog.Info("scanning git log", "dir", s.gitDir)
 		if err := s.scanGitLog(ctx, collect); err != nil {
-			return nil, fmt.Errorf("scan git log: %w", err)
+			return stopped(fmt.Errorf("scan git log: %w", err))
 		}
-		slog.Info("finished git log", "new_ids", len(result)-before, "total_ids", len(result))
-		before = len(result)
+		slog.Info("finished git log", "new_ids", len(seen)-before, "total_ids", len(seen))
+		before = len(seen)
 	}
 
 	scanners := []struct {
@@ -71,37 +164,106 @@ func (s *RepoScanner) ScanRepo(ctx context.Context, teamKey string) ([]string, e
 	for _, sc := range scanners {
 		slog.Info("scanning", "source", sc.name)
 		if err := sc.fn(ctx, collect); err != nil {
-			return nil, fmt.Errorf("scan %s: %w", sc.name, err)
+			return stopped(fmt.Errorf("scan %s: %w", sc.name, err))
 		}
-		slog.Info("finished", "source", sc.name, "new_ids", len(result)-before, "total_ids", len(result))
-		before = len(result)
+		slog.Info("finished", "source", sc.name, "new_ids", len(seen)-before, "total_ids", len(seen))
+		before = len(seen)
 	}
 
-	return result, nil
+	return stopped(nil)
 }
 
+// scanGitLog reads every commit message in gitDir, one at a time. A git that
+// was killed by a signal other than our own timeout is retried; other
+// failures, such as gitDir not being a repository, are reported with git's
+// stderr.
 func (s *RepoScanner) scanGitLog(ctx context.Context, collect func(string)) error {
-	cmd := exec.CommandContext(ctx, "git", "-C", s.gitDir, "log", "--format=%B")
-	out, err := cmd.Output()
+	return s.retry.Do(ctx, func() (bool, error) {
+		gitCtx := ctx
+		if s.gitTimeout > 0 {
+			var cancel context.CancelFunc
+			gitCtx, cancel = context.WithTimeout(ctx, s.gitTimeout)
+			defer cancel()
+		}
+
+		var stderr strings.Builder
+		cmd := exec.CommandContext(gitCtx, "git", "-C", s.gitDir, "log", "--format=%an%x1f%B%x00")
+		cmd.Stderr = &stderr
+		err := readCommits(cmd, func(record string) {
+			author, message, _ := strings.Cut(strings.TrimPrefix(record, "\n"), "\x1f")
+			if !s.authors.Excludes(author) {
+				collect(message)
+			}
+		})
+		switch {
+		case err == nil:
+			return false, nil
+		case ctx.Err() != nil:
+			return false, ctx.Err()
+		case gitCtx.Err() != nil:
+			return false, fmt.Errorf("git log: timed out after %s", s.gitTimeout)
+		}
+
+		var exitErr *exec.ExitError
+		killed := errors.As(err, &exitErr) && exitErr.ExitCode() == -1
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return killed, fmt.Errorf("git log: %w", err)
+	})
+}
+
+// maxCommitMessage bounds how much of a single commit message is buffered.
+const maxCommitMessage = 1 << 20
+
+// readCommits runs cmd, a git log printing NUL-terminated records, passing
+// each record to collect as it arrives.
+func readCommits(cmd *exec.Cmd, collect func(string)) error {
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return fmt.Errorf("git log: %w", err)
+		return err
 	}
-	collect(string(out))
-	return nil
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(make([]byte, 0, 64<<10), maxCommitMessage)
+	sc.Split(splitNUL)
+	for sc.Scan() {
+		collect(sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
+	return cmd.Wait()
 }
 
-func (s *RepoScanner) scanPullRequests(ctx context.Context, collect func(string)) error {
-	var prs []struct {
-		Title string `json:"title"`
-		Body  string `json:"body"`
+func splitNUL(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
 	}
+	return 0, nil, nil
+}
+
+func (s *RepoScanner) scanPullRequests(ctx context.Context, collect func(string)) error {
+	var prs []pullRequest
 	return s.paginate(ctx, "pull requests", s.repoURL("/pulls?state=all"), func(body []byte) (int, error) {
 		if err := json.Unmarshal(body, &prs); err != nil {
 			return 0, err
 		}
 		for _, pr := range prs {
-			collect(pr.Title)
-			collect(pr.Body)
+			if s.authors.Excludes(pr.User.Login) {
+				continue
+			}
+			for _, text := range pr.texts() {
+				collect(text)
+			}
 		}
 		n := len(prs)
 		prs = prs[:0]
@@ -179,36 +341,50 @@ func (s *RepoScanner) paginate(ctx context.Context, source, url string, decode f
 	total := 0
 	for url != "" {
 		page++
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-		if err != nil {
-			return err
-		}
-		req.Header.Set("Accept", "application/vnd.github+json")
-		if s.token != "" {
-			req.Header.Set("Authorization", "Bearer "+s.token)
-		}
+		var (
+			body []byte
+			link string
+		)
+		err := s.retry.Do(ctx, func() (bool, error) {
+			if err := s.limiter.Wait(ctx); err != nil {
+				return false, err
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return false, err
+			}
+			req.Header.Set("Accept", "application/vnd.github+json")
+			if s.token != "" {
+				req.Header.Set("Authorization", "Bearer "+s.token)
+			}
 
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return err
-		}
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return ctx.Err() == nil && retry.Network(err), err
+			}
+			body, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return true, err
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				return retry.Status(resp.StatusCode), fmt.Errorf("GitHub API %s: %s", resp.Status, body)
+			}
+			link = resp.Header.Get("Link")
+			return false, nil
+		})
 		if err != nil {
 			return err
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("GitHub API %s: %s", resp.Status, body)
-		}
-
 		n, err := decode(body)
 		if err != nil {
 			return err
 		}
 		total += n
 
-		url = nextPageURL(resp.Header.Get("Link"))
+		url = nextPageURL(link)
 		if url != "" {
 			slog.Info("fetching next page", "source", source, "page", page+1, "items_so_far", total)
 		}
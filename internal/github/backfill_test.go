@@ -3,13 +3,21 @@ package github
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/retry"
 )
 
 func TestRepoScanner_ScanRepo(t *testing.T) {
@@ -205,6 +213,136 @@ func TestRepoScanner_APIError(t *testing.T) {
 	}
 }
 
+func TestRepoScanner_RetriesServerError(t *testing.T) {
+	calls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/repo/pulls", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]any{{"title": "MIR-9: retry me"}})
+	})
+	emptyHandler := func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]string{})
+	}
+	mux.HandleFunc("/repos/org/repo/issues", emptyHandler)
+	mux.HandleFunc("/repos/org/repo/issues/comments", emptyHandler)
+	mux.HandleFunc("/repos/org/repo/pulls/comments", emptyHandler)
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	scanner := NewRepoScanner("", "org", "repo")
+	scanner.baseURL = srv.URL
+	scanner.SetRetryPolicy(retry.Policy{MaxRetries: 1, Base: time.Millisecond})
+
+	ids, err := scanner.ScanRepo(context.Background(), "MIR")
+	if err != nil {
+		t.Fatalf("ScanRepo: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("pulls requested %d times, want 2", calls)
+	}
+	if len(ids) != 1 || ids[0] != "MIR-9" {
+		t.Fatalf("got %v, want [MIR-9]", ids)
+	}
+}
+
+func TestRepoScanner_BranchNames(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/repo/pulls", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"title": "fix the thing", "body": "", "head": map[string]string{"ref": "feature/MIR-77"}},
+		})
+	})
+	emptyHandler := func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]string{})
+	}
+	mux.HandleFunc("/repos/org/repo/issues", emptyHandler)
+	mux.HandleFunc("/repos/org/repo/issues/comments", emptyHandler)
+	mux.HandleFunc("/repos/org/repo/pulls/comments", emptyHandler)
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	scanner := NewRepoScanner("", "org", "repo")
+	scanner.baseURL = srv.URL
+
+	ids, err := scanner.ScanRepo(context.Background(), "MIR")
+	if err != nil {
+		t.Fatalf("ScanRepo: %v", err)
+	}
+
+	if len(ids) != 1 || ids[0] != "MIR-77" {
+		t.Fatalf("got %v, want [MIR-77]", ids)
+	}
+}
+
+func TestRepoScanner_PullRequestLabels(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/repo/pulls", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"title": "fix the thing", "body": "", "head": map[string]string{"ref": "fix"}, "labels": []map[string]string{{"name": "MIR-55"}}, "milestone": nil},
+		})
+	})
+	emptyHandler := func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]string{})
+	}
+	mux.HandleFunc("/repos/org/repo/issues", emptyHandler)
+	mux.HandleFunc("/repos/org/repo/issues/comments", emptyHandler)
+	mux.HandleFunc("/repos/org/repo/pulls/comments", emptyHandler)
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	scanner := NewRepoScanner("", "org", "repo")
+	scanner.baseURL = srv.URL
+
+	ids, err := scanner.ScanRepo(context.Background(), "MIR")
+	if err != nil {
+		t.Fatalf("ScanRepo: %v", err)
+	}
+
+	if len(ids) != 1 || ids[0] != "MIR-55" {
+		t.Fatalf("got %v, want [MIR-55]", ids)
+	}
+}
+
+func TestRepoScanner_SharedRateLimiter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		json.NewEncoder(w).Encode([]map[string]string{})
+	}))
+	defer srv.Close()
+
+	// Two scanners make four requests each; at 50 rps with no burst, the
+	// seven after the first are spaced 20ms apart.
+	limiter := NewRateLimiter(50, 1)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 2 {
+		scanner := NewRepoScanner("", "org", "repo")
+		scanner.baseURL = srv.URL
+		scanner.SetRateLimiter(limiter)
+		wg.Go(func() {
+			if _, err := scanner.ScanRepo(context.Background(), "MIR"); err != nil {
+				t.Errorf("ScanRepo: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if n := calls.Load(); n != 8 {
+		t.Fatalf("requests = %d, want 8", n)
+	}
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("8 requests took %v, want at least 140ms under the rate limit", elapsed)
+	}
+}
+
 func TestRepoScanner_AuthHeader(t *testing.T) {
 	var gotAuth string
 	mux := http.NewServeMux()
@@ -281,3 +419,169 @@ func initTestRepo(t *testing.T, messages ...string) string {
 	}
 	return gitDir
 }
+
+func TestRepoScanner_GitLogTimeout(t *testing.T) {
+	scanner := NewRepoScanner("", "org", "repo")
+	scanner.SetGitDir(initTestRepo(t, "MIR-1: first commit"))
+	scanner.SetGitTimeout(time.Nanosecond)
+
+	err := scanner.scanGitLog(context.Background(), func(string) {
+		t.Error("collected output from a timed-out git log")
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("scanGitLog error = %v, want a timeout", err)
+	}
+}
+
+func TestRepoScanner_GitLogStderr(t *testing.T) {
+	scanner := NewRepoScanner("", "org", "repo")
+	scanner.SetGitDir(t.TempDir())
+	scanner.SetRetryPolicy(retry.Policy{})
+
+	err := scanner.scanGitLog(context.Background(), func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "not a git repository") {
+		t.Errorf("scanGitLog error = %v, want git's stderr", err)
+	}
+}
+
+func TestRepoScanner_ScanRepoFuncStreams(t *testing.T) {
+	labeled := make(chan string, 10)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/repo/pulls", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]string{{"title": "MIR-1: first"}})
+	})
+	// The scan can't get past issues until MIR-1 from the pull requests has
+	// been handed to the callback.
+	mux.HandleFunc("/repos/org/repo/issues", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-labeled:
+		case <-time.After(time.Second):
+			t.Error("MIR-1 was not streamed before the scan finished")
+		}
+		json.NewEncoder(w).Encode([]map[string]string{{"title": "MIR-2: second"}})
+	})
+	empty := func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "[]") }
+	mux.HandleFunc("/repos/org/repo/issues/comments", empty)
+	mux.HandleFunc("/repos/org/repo/pulls/comments", empty)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	scanner := NewRepoScanner("", "org", "repo")
+	scanner.baseURL = srv.URL
+
+	var got []string
+	err := scanner.ScanRepoFunc(context.Background(), "MIR", func(id string) error {
+		got = append(got, id)
+		labeled <- id
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanRepoFunc: %v", err)
+	}
+	if want := []string{"MIR-1", "MIR-2"}; !slices.Equal(got, want) {
+		t.Errorf("streamed %v, want %v", got, want)
+	}
+}
+
+func TestRepoScanner_ScanRepoFuncStopsOnError(t *testing.T) {
+	gitDir := initTestRepo(t, "MIR-1: first", "MIR-2: second")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		fmt.Fprint(w, "[]")
+	}))
+	defer srv.Close()
+
+	scanner := NewRepoScanner("", "org", "repo")
+	scanner.baseURL = srv.URL
+	scanner.SetGitDir(gitDir)
+
+	errLabel := fmt.Errorf("linear is down")
+	err := scanner.ScanRepoFunc(context.Background(), "MIR", func(string) error {
+		return errLabel
+	})
+	if !errors.Is(err, errLabel) {
+		t.Errorf("ScanRepoFunc error = %v, want %v", err, errLabel)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("GitHub called %d times after the callback failed", n)
+	}
+}
+
+func TestRepoScanner_AuthorFilter(t *testing.T) {
+	gitDir := initTestRepo(t, "MIR-1: by a person")
+	cmd := exec.Command("git", "-C", gitDir, "commit", "--allow-empty", "-m", "Bump deps (MIR-2)",
+		"--author", "dependabot[bot] <support@github.com>")
+	cmd.Env = append(os.Environ(), "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@test.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %s\n%s", err, out)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/repo/pulls", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"title": "MIR-3: feature", "user": {"login": "alice"}},
+			{"title": "Bump x, see MIR-4", "user": {"login": "dependabot[bot]"}},
+			{"title": "MIR-5 cleanup", "user": {"login": "Release-Robot"}}
+		]`)
+	})
+	empty := func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "[]") }
+	mux.HandleFunc("/repos/org/repo/issues", empty)
+	mux.HandleFunc("/repos/org/repo/issues/comments", empty)
+	mux.HandleFunc("/repos/org/repo/pulls/comments", empty)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		filter AuthorFilter
+		want   []string
+	}{
+		{"no filter", AuthorFilter{}, []string{"MIR-2", "MIR-1", "MIR-3", "MIR-4", "MIR-5"}},
+		{"bots", AuthorFilter{Bots: true}, []string{"MIR-1", "MIR-3", "MIR-5"}},
+		{"bots and logins", AuthorFilter{Bots: true, Logins: []string{"release-robot"}}, []string{"MIR-1", "MIR-3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewRepoScanner("", "org", "repo")
+			scanner.baseURL = srv.URL
+			scanner.SetGitDir(gitDir)
+			scanner.SetAuthorFilter(tt.filter)
+
+			ids, err := scanner.ScanRepo(context.Background(), "MIR")
+			if err != nil {
+				t.Fatalf("ScanRepo: %v", err)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("ScanRepo = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveToken(t *testing.T) {
+	// Stand in for the gh CLI with a script earlier on PATH.
+	bin := t.TempDir()
+	script := "#!/bin/sh\n[ \"$1 $2\" = \"auth token\" ] && echo gh-token\n"
+	if err := os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	t.Setenv("GITHUB_TOKEN", "env-token")
+	if got := ResolveToken(); got != "env-token" {
+		t.Errorf("with GITHUB_TOKEN set, ResolveToken() = %q, want env-token", got)
+	}
+
+	t.Setenv("GITHUB_TOKEN", "")
+	if got := ResolveToken(); got != "gh-token" {
+		t.Errorf("without GITHUB_TOKEN, ResolveToken() = %q, want gh-token", got)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if got := ResolveToken(); got != "" {
+		t.Errorf("without gh, ResolveToken() = %q, want empty", got)
+	}
+}
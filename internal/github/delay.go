@@ -0,0 +1,150 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// publishQueue holds identifiers back for a grace period before they are
+// published. Each identifier has one timer, which a new reference restarts,
+// so repeated deliveries publish it once. References are tracked by the
+// comment, issue, pull request, or review that made them: if an edit drops
+// the reference, or the subject is deleted, before the timer fires and
+// nothing else references the identifier, it isn't published.
+type publishQueue struct {
+	delay   time.Duration
+	publish func(identifier string)
+
+	mu       sync.Mutex
+	pending  map[string]*pendingPublish
+	subjects map[string][]string // subject key -> identifiers it references
+}
+
+type pendingPublish struct {
+	timer    *time.Timer
+	subjects map[string]bool // "" for references that can't be withdrawn, like commits
+}
+
+func newPublishQueue(delay time.Duration, publish func(identifier string)) *publishQueue {
+	return &publishQueue{
+		delay:    delay,
+		publish:  publish,
+		pending:  make(map[string]*pendingPublish),
+		subjects: make(map[string][]string),
+	}
+}
+
+// schedule records that subject now references exactly identifiers,
+// withdrawing its earlier references that are gone. An empty subject key
+// means the references can't be edited later.
+func (q *publishQueue) schedule(subject string, identifiers []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if subject != "" {
+		for _, id := range q.subjects[subject] {
+			if !slices.Contains(identifiers, id) {
+				q.withdraw(id, subject)
+			}
+		}
+		if len(identifiers) == 0 {
+			delete(q.subjects, subject)
+		} else {
+			q.subjects[subject] = identifiers
+		}
+	}
+
+	for _, id := range identifiers {
+		p, ok := q.pending[id]
+		if ok && p.timer.Stop() {
+			p.timer.Reset(q.delay)
+		} else {
+			// A timer that couldn't be stopped has already fired, and fire
+			// discards a publish that is no longer pending, so replace it.
+			next := &pendingPublish{subjects: make(map[string]bool)}
+			if ok {
+				maps.Copy(next.subjects, p.subjects)
+			}
+			next.timer = time.AfterFunc(q.delay, func() { q.fire(id, next) })
+			q.pending[id] = next
+			p = next
+		}
+		p.subjects[subject] = true
+	}
+}
+
+// withdraw drops subject's reference to identifier, cancelling its publish
+// if nothing else references it. The caller holds mu.
+func (q *publishQueue) withdraw(identifier, subject string) {
+	p, ok := q.pending[identifier]
+	if !ok {
+		return
+	}
+	delete(p.subjects, subject)
+	if len(p.subjects) == 0 {
+		p.timer.Stop()
+		delete(q.pending, identifier)
+	}
+}
+
+func (q *publishQueue) fire(identifier string, p *pendingPublish) {
+	q.mu.Lock()
+	if q.pending[identifier] != p {
+		q.mu.Unlock()
+		return
+	}
+	delete(q.pending, identifier)
+	for subject := range p.subjects {
+		if subject == "" {
+			continue
+		}
+		ids := slices.DeleteFunc(slices.Clone(q.subjects[subject]), func(id string) bool { return id == identifier })
+		if len(ids) == 0 {
+			delete(q.subjects, subject)
+		} else {
+			q.subjects[subject] = ids
+		}
+	}
+	q.mu.Unlock()
+
+	q.publish(identifier)
+}
+
+// subjectKey names what a delivery's references belong to, so a later
+// delivery about the same comment, issue, pull request, or review can
+// replace them. It is empty for deliveries whose references can't change,
+// such as pushes. deleted reports whether the subject was deleted.
+func subjectKey(eventType string, body []byte) (key string, deleted bool) {
+	var d deliverySubjects
+	if json.Unmarshal(body, &d) != nil {
+		return "", false
+	}
+	repo := d.Repository.FullName
+	switch eventType {
+	case "pull_request":
+		if d.PullRequest != nil {
+			key = fmt.Sprintf("%s/pulls/%d", repo, d.PullRequest.Number)
+		}
+	case "pull_request_review":
+		if d.Review != nil {
+			key = fmt.Sprintf("%s/reviews/%d", repo, d.Review.ID)
+		}
+	case "pull_request_review_comment":
+		if d.Comment != nil {
+			key = fmt.Sprintf("%s/pulls/comments/%d", repo, d.Comment.ID)
+		}
+	case "issues":
+		if d.Issue != nil {
+			key = fmt.Sprintf("%s/issues/%d", repo, d.Issue.Number)
+		}
+	case "issue_comment":
+		if d.Comment != nil {
+			key = fmt.Sprintf("%s/issues/comments/%d", repo, d.Comment.ID)
+		}
+	}
+	return key, key != "" && d.Action == "deleted"
+}
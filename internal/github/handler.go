@@ -9,29 +9,180 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/linearapi"
 )
 
 const maxBodySize = 1 << 20 // 1 MB
 
+const DefaultLabelTimeout = 30 * time.Second
+
+// DefaultQueueSize and DefaultWorkers size the async queue unless
+// SetAsyncQueue says otherwise.
+const (
+	DefaultQueueSize = 100
+	DefaultWorkers   = 4
+)
+
 type Labeler interface {
-	EnsurePublicLabel(ctx context.Context, identifier string) error
+	EnsurePublicLabel(ctx context.Context, identifier string) (linearapi.LabelResult, error)
+}
+
+// Unpublisher takes issues off the public site.
+type Unpublisher interface {
+	RemovePublicLabel(ctx context.Context, identifier string) error
 }
 
 type WebhookHandler struct {
-	secret  []byte
-	teamKey string
-	labeler Labeler
+	secret       []byte
+	teamPattern  *regexp.Regexp
+	repoPatterns map[string]*regexp.Regexp
+	labeler      Labeler
+	unpublisher  Unpublisher
+	labelTimeout time.Duration
+	async        bool
+	minNumbers   MinNumbers
+	cache        linearapi.Cache
+	verifier     *Verifier
+	queue        *publishQueue
+
+	jobsOnce sync.Once
+	jobs     chan func() // async deliveries waiting for a worker
 }
 
 func NewWebhookHandler(secret, teamKey string, labeler Labeler) *WebhookHandler {
 	return &WebhookHandler{
-		secret:  []byte(secret),
-		teamKey: teamKey,
-		labeler: labeler,
+		secret:       []byte(secret),
+		teamPattern:  teamIssuePattern(teamKey),
+		labeler:      labeler,
+		labelTimeout: DefaultLabelTimeout,
 	}
 }
 
+// SetLabelTimeout bounds the labeling done for a single delivery. Labeling
+// runs detached from the request so a disconnecting sender doesn't abort it.
+func (h *WebhookHandler) SetLabelTimeout(d time.Duration) {
+	h.labelTimeout = d
+}
+
+// SetMinNumbers stops the handler from publishing issues numbered below
+// their team's minimum. Reverts may still unpublish them.
+func (h *WebhookHandler) SetMinNumbers(m MinNumbers) {
+	h.minNumbers = m
+}
+
+// SetCache makes the handler drop issues it labels or unlabels from c, so
+// their pages change on the next request instead of when the entry expires.
+func (h *WebhookHandler) SetCache(c linearapi.Cache) {
+	h.cache = c
+}
+
+// SetVerifier makes the handler confirm with v that what a delivery
+// describes exists before acting on it, and take identifiers from GitHub's
+// copy of its text. Deliveries that fail the check, or can't be checked, are
+// ignored. This costs GitHub API calls and latency.
+func (h *WebhookHandler) SetVerifier(v *Verifier) {
+	h.verifier = v
+}
+
+// SetPublishDelay holds newly referenced issues back for d before labeling
+// them, so a mistyped reference that is edited away or deleted within d
+// doesn't publish anything. Repeated references within d publish an issue
+// once, d after the last one. Reverts still unpublish at once. Zero labels
+// immediately.
+func (h *WebhookHandler) SetPublishDelay(d time.Duration) {
+	if d <= 0 {
+		h.queue = nil
+		return
+	}
+	h.queue = newPublishQueue(d, h.publishDelayed)
+}
+
+// publishDelayed labels an identifier whose grace period has passed.
+func (h *WebhookHandler) publishDelayed(identifier string) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.labelTimeout)
+	defer cancel()
+	h.process(ctx, "delayed", []string{identifier}, nil)
+}
+
+// SetAsync makes the handler answer 202 Accepted as soon as a delivery is
+// verified and label it afterwards, for senders that time out on slow
+// responses. The response then can't say how labeling went.
+func (h *WebhookHandler) SetAsync(async bool) {
+	h.async = async
+}
+
+// SetAsyncQueue sets how many async deliveries may wait for labeling and how
+// many workers label them. A delivery that finds the queue full gets 503
+// Service Unavailable, so it shows as failed and can be redelivered, instead
+// of piling up goroutines. It takes effect once; call it before serving.
+func (h *WebhookHandler) SetAsyncQueue(size, workers int) {
+	h.jobsOnce.Do(func() {
+		h.jobs = make(chan func(), max(size, 0))
+		for range max(workers, 1) {
+			go func() {
+				for job := range h.jobs {
+					job()
+				}
+			}()
+		}
+	})
+}
+
+// deliverySummary is the response body for a delivery handled synchronously.
+// Matched counts identifiers found for the team; Processed counts those
+// labeled or unlabeled without error, and Scheduled those left to be labeled
+// after the publish delay.
+type deliverySummary struct {
+	Event     string `json:"event"`
+	Matched   int    `json:"matched"`
+	Processed int    `json:"processed"`
+	Scheduled int    `json:"scheduled,omitempty"`
+}
+
+// SetRepoTeams routes each delivery by the repository it came from: events
+// from a repo in repoTeams only act on identifiers of that repo's team keys,
+// and events from any other repo are ignored. Repo names are full names like
+// "org/frontend", matched case-insensitively. This replaces the single team
+// key passed to NewWebhookHandler.
+func (h *WebhookHandler) SetRepoTeams(repoTeams map[string][]string) {
+	h.repoPatterns = make(map[string]*regexp.Regexp, len(repoTeams))
+	for repo, keys := range repoTeams {
+		h.repoPatterns[strings.ToLower(repo)] = teamIssuePattern(keys...)
+	}
+}
+
+// teamPatternFor returns the pattern for identifiers a delivery may act on,
+// or nil if its repository isn't mapped.
+func (h *WebhookHandler) teamPatternFor(body []byte) *regexp.Regexp {
+	if h.repoPatterns == nil {
+		return h.teamPattern
+	}
+	var payload struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return nil
+	}
+	return h.repoPatterns[strings.ToLower(payload.Repository.FullName)]
+}
+
+// SetUnpublisher enables removing the public label from issues referenced
+// only by reverts: commits whose message starts with Revert "..." or says
+// "This reverts commit", and merged pull requests titled Revert "...". An
+// issue also mentioned by a non-revert commit in the same delivery is left
+// alone, since it was likely relanded.
+func (h *WebhookHandler) SetUnpublisher(u Unpublisher) {
+	h.unpublisher = u
+}
+
 func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
 	if err != nil {
@@ -45,27 +196,154 @@ func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	eventType := r.Header.Get("X-GitHub-Event")
+	var identifiers, reverted []string
+	teamPattern := h.teamPatternFor(body)
+	if teamPattern != nil {
+		identifiers, reverted = h.match(teamPattern, eventType, body)
+	}
+
+	matched := len(identifiers) + len(reverted)
+
+	// With a publish delay, a delivery replaces what its subject referenced
+	// before. Withdrawing references needs no verification: at worst it
+	// delays publishing until the next mention.
+	var subject string
+	if h.queue != nil {
+		var deleted bool
+		subject, deleted = subjectKey(eventType, body)
+		if deleted {
+			identifiers = nil
+		}
+		if subject != "" && len(identifiers) == 0 {
+			h.queue.schedule(subject, nil)
+		}
+	}
+
+	scheduled := 0
+	run := func(ctx context.Context) int {
+		if len(identifiers)+len(reverted) == 0 {
+			return 0
+		}
+		identifiers, reverted := identifiers, reverted
+		if h.verifier != nil {
+			// Act only on what GitHub's copy of the delivery mentions.
+			verified, ok := h.verify(ctx, eventType, body)
+			if !ok {
+				return 0
+			}
+			identifiers, reverted = h.match(teamPattern, eventType, verified)
+		}
+		if h.queue != nil && len(identifiers) > 0 {
+			h.queue.schedule(subject, identifiers)
+			scheduled = len(identifiers)
+			return h.process(ctx, eventType, nil, reverted)
+		}
+		return h.process(ctx, eventType, identifiers, reverted)
+	}
+	if h.async {
+		h.SetAsyncQueue(DefaultQueueSize, DefaultWorkers)
+		job := func() {
+			// The time budget starts when a worker picks the delivery up.
+			ctx, cancel := context.WithTimeout(context.Background(), h.labelTimeout)
+			defer cancel()
+			run(ctx)
+		}
+		select {
+		case h.jobs <- job:
+			writeJSON(w, http.StatusAccepted, map[string]bool{"accepted": true})
+		default:
+			slog.Warn("webhook queue full, asking sender to retry", "event", eventType)
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "webhook queue full", http.StatusServiceUnavailable)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), h.labelTimeout)
+	defer cancel()
+	processed := run(ctx)
+	writeJSON(w, http.StatusOK, deliverySummary{
+		Event:     eventType,
+		Matched:   matched,
+		Processed: processed,
+		Scheduled: scheduled,
+	})
+}
+
+// match returns the identifiers a delivery should publish and, when
+// unpublishing is enabled, those referenced only by reverts.
+func (h *WebhookHandler) match(teamPattern *regexp.Regexp, eventType string, body []byte) (identifiers, reverted []string) {
 	texts := extractTexts(eventType, body)
 
-	var allText strings.Builder
-	for _, t := range texts {
-		allText.WriteString(t)
-		allText.WriteByte('\n')
+	var reverts []string
+	if h.unpublisher != nil {
+		reverts = extractRevertTexts(eventType, body)
+		texts = slices.DeleteFunc(texts, func(t string) bool {
+			return slices.Contains(reverts, t)
+		})
 	}
 
-	identifiers := ScanIdentifiers(allText.String())
+	mentioned := scanUnique(teamPattern, strings.Join(texts, "\n"))
+	reverted = slices.DeleteFunc(scanUnique(teamPattern, strings.Join(reverts, "\n")), func(id string) bool {
+		return slices.Contains(mentioned, id)
+	})
+	return h.minNumbers.Filter(mentioned), reverted
+}
 
-	prefix := strings.ToUpper(h.teamKey) + "-"
+// verify returns the delivery as the verifier rebuilt it from GitHub, and
+// false if it didn't pass.
+func (h *WebhookHandler) verify(ctx context.Context, eventType string, body []byte) ([]byte, bool) {
+	verified, ok, err := h.verifier.Verify(ctx, eventType, body)
+	if err != nil {
+		slog.Error("failed to verify webhook delivery, ignoring it", "event", eventType, "error", err)
+		return nil, false
+	}
+	if !ok {
+		slog.Warn("webhook delivery doesn't match GitHub, ignoring it", "event", eventType)
+	}
+	return verified, ok
+}
+
+// process labels and unlabels the matched identifiers, returning how many
+// succeeded.
+func (h *WebhookHandler) process(ctx context.Context, eventType string, identifiers, reverted []string) int {
+	processed, published := 0, 0
 	for _, id := range identifiers {
-		if !strings.HasPrefix(id, prefix) {
+		result, err := h.labeler.EnsurePublicLabel(ctx, id)
+		if err != nil {
+			slog.Error("failed to ensure public label", "identifier", id, "error", err)
 			continue
 		}
-		if err := h.labeler.EnsurePublicLabel(r.Context(), id); err != nil {
-			slog.Error("failed to ensure public label", "identifier", id, "error", err)
+		processed++
+		if result == linearapi.LabelApplied {
+			published++
+			h.forget(id)
+		}
+	}
+	if published > 0 {
+		slog.Info("published issues from webhook", "event", eventType, "published", published)
+	}
+	for _, id := range reverted {
+		if err := h.unpublisher.RemovePublicLabel(ctx, id); err != nil {
+			slog.Error("failed to remove public label", "identifier", id, "error", err)
+			continue
 		}
+		processed++
+		h.forget(id)
 	}
+	return processed
+}
 
-	w.WriteHeader(http.StatusOK)
+func (h *WebhookHandler) forget(identifier string) {
+	if h.cache != nil {
+		h.cache.Delete(identifier)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
 }
 
 func (h *WebhookHandler) verifySignature(body []byte, signature string) bool {
@@ -100,8 +378,43 @@ func extractTexts(eventType string, body []byte) []string {
 	}
 }
 
+func isRevert(message string) bool {
+	return strings.HasPrefix(message, `Revert "`) || strings.Contains(message, "This reverts commit")
+}
+
+// extractRevertTexts returns the texts of a delivery that undo earlier work:
+// revert commit messages in a push, or a merged revert pull request.
+func extractRevertTexts(eventType string, body []byte) []string {
+	switch eventType {
+	case "push":
+		var reverts []string
+		for _, msg := range extractPushTexts(body) {
+			if isRevert(msg) {
+				reverts = append(reverts, msg)
+			}
+		}
+		return reverts
+	case "pull_request":
+		var payload struct {
+			Action      string      `json:"action"`
+			PullRequest pullRequest `json:"pull_request"`
+		}
+		if json.Unmarshal(body, &payload) != nil {
+			return nil
+		}
+		pr := payload.PullRequest
+		if payload.Action != "closed" || !pr.Merged || !isRevert(pr.Title) {
+			return nil
+		}
+		return pr.texts()
+	default:
+		return nil
+	}
+}
+
 func extractPushTexts(body []byte) []string {
 	var payload struct {
+		Ref     string `json:"ref"`
 		Commits []struct {
 			Message string `json:"message"`
 		} `json:"commits"`
@@ -109,24 +422,53 @@ func extractPushTexts(body []byte) []string {
 	if json.Unmarshal(body, &payload) != nil {
 		return nil
 	}
-	texts := make([]string, 0, len(payload.Commits))
+	texts := make([]string, 0, len(payload.Commits)+1)
+	texts = append(texts, strings.TrimPrefix(payload.Ref, "refs/heads/"))
 	for _, c := range payload.Commits {
 		texts = append(texts, c.Message)
 	}
 	return texts
 }
 
+// pullRequest holds the fields of a pull request that may mention an
+// identifier. Webhook payloads and the REST API share this shape.
+type pullRequest struct {
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	Merged bool   `json:"merged"`
+	Head   struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Milestone *struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+}
+
+func (pr *pullRequest) texts() []string {
+	texts := []string{pr.Title, pr.Body, pr.Head.Ref}
+	for _, l := range pr.Labels {
+		texts = append(texts, l.Name)
+	}
+	if pr.Milestone != nil {
+		texts = append(texts, pr.Milestone.Title)
+	}
+	return texts
+}
+
 func extractPullRequestTexts(body []byte) []string {
 	var payload struct {
-		PullRequest struct {
-			Title string `json:"title"`
-			Body  string `json:"body"`
-		} `json:"pull_request"`
+		PullRequest pullRequest `json:"pull_request"`
 	}
 	if json.Unmarshal(body, &payload) != nil {
 		return nil
 	}
-	return []string{payload.PullRequest.Title, payload.PullRequest.Body}
+	return payload.PullRequest.texts()
 }
 
 func extractIssueTexts(body []byte) []string {
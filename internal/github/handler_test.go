@@ -8,18 +8,36 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/linearapi"
 )
 
 type mockLabeler struct {
+	called  []string
+	ctxErrs []error
+	err     error
+}
+
+func (m *mockLabeler) EnsurePublicLabel(ctx context.Context, identifier string) (linearapi.LabelResult, error) {
+	m.called = append(m.called, identifier)
+	m.ctxErrs = append(m.ctxErrs, ctx.Err())
+	if m.err != nil {
+		return "", m.err
+	}
+	return linearapi.LabelApplied, nil
+}
+
+type mockUnpublisher struct {
 	called []string
-	err    error
 }
 
-func (m *mockLabeler) EnsurePublicLabel(_ context.Context, identifier string) error {
+func (m *mockUnpublisher) RemovePublicLabel(_ context.Context, identifier string) error {
 	m.called = append(m.called, identifier)
-	return m.err
+	return nil
 }
 
 func sign(secret, body string) string {
@@ -84,6 +102,47 @@ func TestWebhookHandler_PushEvent(t *testing.T) {
 	}
 }
 
+type mockCache struct {
+	deleted []string
+}
+
+func (m *mockCache) Delete(identifier string) {
+	m.deleted = append(m.deleted, identifier)
+}
+
+func TestWebhookHandler_DeletesLabeledFromCache(t *testing.T) {
+	mock := &mockLabeler{}
+	cache := &mockCache{}
+	handler := NewWebhookHandler("secret", "MIR", mock)
+	handler.SetUnpublisher(&mockUnpublisher{})
+	handler.SetCache(cache)
+
+	body := `{"commits":[{"message":"Fix MIR-42"},{"message":"Revert \"Fix MIR-7\""}]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+	req.Header.Set("X-GitHub-Event", "push")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if want := []string{"MIR-42", "MIR-7"}; !slices.Equal(cache.deleted, want) {
+		t.Errorf("deleted = %v, want %v", cache.deleted, want)
+	}
+}
+
+func TestWebhookHandler_SkipsNumberZero(t *testing.T) {
+	mock := &mockLabeler{}
+	handler := NewWebhookHandler("secret", "MIR", mock)
+
+	body := `{"commits":[{"message":"MIR-0 placeholder, real fix is MIR-1"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+	req.Header.Set("X-GitHub-Event", "push")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !slices.Equal(mock.called, []string{"MIR-1"}) {
+		t.Errorf("labeled = %v, want [MIR-1]", mock.called)
+	}
+}
+
 func TestWebhookHandler_PullRequestEvent(t *testing.T) {
 	mock := &mockLabeler{}
 	handler := NewWebhookHandler("secret", "MIR", mock)
@@ -104,6 +163,57 @@ func TestWebhookHandler_PullRequestEvent(t *testing.T) {
 	}
 }
 
+func TestWebhookHandler_PushBranchName(t *testing.T) {
+	mock := &mockLabeler{}
+	handler := NewWebhookHandler("secret", "MIR", mock)
+
+	body := `{"ref":"refs/heads/MIR-88-branch","commits":[{"message":"wip"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+	req.Header.Set("X-GitHub-Event", "push")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if len(mock.called) != 1 || mock.called[0] != "MIR-88" {
+		t.Fatalf("called = %v, want [MIR-88]", mock.called)
+	}
+}
+
+func TestWebhookHandler_PullRequestBranchName(t *testing.T) {
+	mock := &mockLabeler{}
+	handler := NewWebhookHandler("secret", "MIR", mock)
+
+	body := `{"pull_request":{"title":"fix the thing","body":"","head":{"ref":"feature/MIR-77"}}}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+	req.Header.Set("X-GitHub-Event", "pull_request")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if len(mock.called) != 1 || mock.called[0] != "MIR-77" {
+		t.Fatalf("called = %v, want [MIR-77]", mock.called)
+	}
+}
+
+func TestWebhookHandler_PullRequestLabelsAndMilestone(t *testing.T) {
+	mock := &mockLabeler{}
+	handler := NewWebhookHandler("secret", "MIR", mock)
+
+	body := `{"pull_request":{"title":"fix the thing","body":"","head":{"ref":"fix"},"labels":[{"name":"MIR-55"}],"milestone":{"title":"MIR-56 launch"}}}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+	req.Header.Set("X-GitHub-Event", "pull_request")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if len(mock.called) != 2 || mock.called[0] != "MIR-55" || mock.called[1] != "MIR-56" {
+		t.Fatalf("called = %v, want [MIR-55 MIR-56]", mock.called)
+	}
+}
+
 func TestWebhookHandler_IssuesEvent(t *testing.T) {
 	mock := &mockLabeler{}
 	handler := NewWebhookHandler("secret", "MIR", mock)
@@ -171,6 +281,23 @@ func TestWebhookHandler_TeamKeyFilter(t *testing.T) {
 	}
 }
 
+func TestWebhookHandler_AlphanumericTeamKey(t *testing.T) {
+	mock := &mockLabeler{}
+	handler := NewWebhookHandler("secret", "WEB2", mock)
+
+	body := `{"commits":[{"message":"Fix WEB2-3, not WEB-3"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+	req.Header.Set("X-GitHub-Event", "push")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if len(mock.called) != 1 || mock.called[0] != "WEB2-3" {
+		t.Errorf("called = %v, want [WEB2-3]", mock.called)
+	}
+}
+
 func TestWebhookHandler_PRReviewEvent(t *testing.T) {
 	mock := &mockLabeler{}
 	handler := NewWebhookHandler("secret", "MIR", mock)
@@ -254,3 +381,525 @@ func TestWebhookHandler_LabelerError(t *testing.T) {
 		t.Errorf("status = %d, want %d (should return 200 even on labeler error)", rr.Code, http.StatusOK)
 	}
 }
+
+func TestWebhookHandler_DetachedLabelContext(t *testing.T) {
+	mock := &mockLabeler{}
+	handler := NewWebhookHandler("secret", "MIR", mock)
+	handler.SetLabelTimeout(time.Minute)
+
+	body := `{"commits":[{"message":"Fix MIR-42"}]}`
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/webhook/github", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+	req.Header.Set("X-GitHub-Event", "push")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if len(mock.called) != 1 {
+		t.Fatalf("expected 1 call, got %d: %v", len(mock.called), mock.called)
+	}
+	if mock.ctxErrs[0] != nil {
+		t.Errorf("labeler context error = %v, want nil despite cancelled request", mock.ctxErrs[0])
+	}
+}
+
+func TestWebhookHandler_Reverts(t *testing.T) {
+	tests := []struct {
+		name          string
+		event         string
+		body          string
+		unpublish     bool
+		wantLabeled   []string
+		wantUnlabeled []string
+	}{
+		{
+			name:          "revert commit",
+			event:         "push",
+			body:          `{"commits":[{"message":"Revert \"MIR-42: add feature\"\n\nThis reverts commit abc123."},{"message":"Fix MIR-7"}]}`,
+			unpublish:     true,
+			wantLabeled:   []string{"MIR-7"},
+			wantUnlabeled: []string{"MIR-42"},
+		},
+		{
+			name:        "disabled",
+			event:       "push",
+			body:        `{"commits":[{"message":"Revert \"MIR-42: add feature\""}]}`,
+			wantLabeled: []string{"MIR-42"},
+		},
+		{
+			name:        "relanded in same push",
+			event:       "push",
+			body:        `{"commits":[{"message":"Revert \"MIR-42: add feature\""},{"message":"MIR-42: add feature again"}]}`,
+			unpublish:   true,
+			wantLabeled: []string{"MIR-42"},
+		},
+		{
+			name:          "merged revert PR",
+			event:         "pull_request",
+			body:          `{"action":"closed","pull_request":{"title":"Revert \"MIR-42: add feature\"","merged":true,"head":{"ref":"revert-1"}}}`,
+			unpublish:     true,
+			wantUnlabeled: []string{"MIR-42"},
+		},
+		{
+			name:      "open revert PR",
+			event:     "pull_request",
+			body:      `{"action":"opened","pull_request":{"title":"Revert \"MIR-42: add feature\"","head":{"ref":"revert-1"}}}`,
+			unpublish: true,
+			// Not merged yet, so it is an ordinary reference.
+			wantLabeled: []string{"MIR-42"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labeler := &mockLabeler{}
+			unpublisher := &mockUnpublisher{}
+			handler := NewWebhookHandler("secret", "MIR", labeler)
+			if tt.unpublish {
+				handler.SetUnpublisher(unpublisher)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(tt.body))
+			req.Header.Set("X-Hub-Signature-256", sign("secret", tt.body))
+			req.Header.Set("X-GitHub-Event", tt.event)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if !slices.Equal(labeler.called, tt.wantLabeled) {
+				t.Errorf("labeled = %v, want %v", labeler.called, tt.wantLabeled)
+			}
+			if !slices.Equal(unpublisher.called, tt.wantUnlabeled) {
+				t.Errorf("unlabeled = %v, want %v", unpublisher.called, tt.wantUnlabeled)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_RepoTeams(t *testing.T) {
+	tests := []struct {
+		name string
+		repo string
+		want []string
+	}{
+		{name: "frontend", repo: "org/frontend", want: []string{"WEB-1"}},
+		{name: "backend", repo: "org/backend", want: []string{"API-2"}},
+		{name: "case insensitive", repo: "Org/Frontend", want: []string{"WEB-1"}},
+		{name: "unmapped", repo: "org/docs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labeler := &mockLabeler{}
+			handler := NewWebhookHandler("secret", "MIR", labeler)
+			handler.SetRepoTeams(map[string][]string{
+				"org/frontend": {"WEB"},
+				"org/backend":  {"API"},
+			})
+
+			body := fmt.Sprintf(`{"repository":{"full_name":%q},"commits":[{"message":"WEB-1 and API-2 and MIR-3"}]}`, tt.repo)
+			req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+			req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+			req.Header.Set("X-GitHub-Event", "push")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rr.Code, http.StatusOK)
+			}
+			if !slices.Equal(labeler.called, tt.want) {
+				t.Errorf("labeled = %v, want %v", labeler.called, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_Summary(t *testing.T) {
+	mock := &mockLabeler{}
+	handler := NewWebhookHandler("secret", "MIR", mock)
+
+	body := `{"commits":[{"message":"Fix MIR-42 and MIR-7"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+	req.Header.Set("X-GitHub-Event", "push")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	want := `{"event":"push","matched":2,"processed":2}`
+	if got := strings.TrimSpace(rr.Body.String()); got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
+
+type chanLabeler chan string
+
+func (c chanLabeler) EnsurePublicLabel(_ context.Context, identifier string) (linearapi.LabelResult, error) {
+	c <- identifier
+	return linearapi.LabelApplied, nil
+}
+
+func TestWebhookHandler_Async(t *testing.T) {
+	labeled := make(chanLabeler, 1)
+	handler := NewWebhookHandler("secret", "MIR", labeled)
+	handler.SetAsync(true)
+
+	body := `{"commits":[{"message":"Fix MIR-42"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+	req.Header.Set("X-GitHub-Event", "push")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusAccepted)
+	}
+	if got := strings.TrimSpace(rr.Body.String()); got != `{"accepted":true}` {
+		t.Errorf("body = %s, want {\"accepted\":true}", got)
+	}
+
+	select {
+	case id := <-labeled:
+		if id != "MIR-42" {
+			t.Errorf("labeled %q, want MIR-42", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("MIR-42 was not labeled after the response")
+	}
+}
+
+// detachedLabeler blocks until release is closed, then reports the error of
+// the context it was called with.
+type detachedLabeler struct {
+	release chan struct{}
+	ctxErr  chan error
+}
+
+func (d detachedLabeler) EnsurePublicLabel(ctx context.Context, _ string) (linearapi.LabelResult, error) {
+	<-d.release
+	d.ctxErr <- ctx.Err()
+	return linearapi.LabelApplied, nil
+}
+
+func TestWebhookHandler_AsyncOutlivesRequest(t *testing.T) {
+	labeler := detachedLabeler{release: make(chan struct{}), ctxErr: make(chan error, 1)}
+	handler := NewWebhookHandler("secret", "MIR", labeler)
+	handler.SetAsync(true)
+
+	body := `{"commits":[{"message":"Fix MIR-42"}]}`
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequestWithContext(reqCtx, http.MethodPost, "/webhook/github", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+	req.Header.Set("X-GitHub-Event", "push")
+	rr := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(rr, req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		close(labeler.release)
+		t.Fatal("ServeHTTP waited for labeling")
+	}
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusAccepted)
+	}
+
+	// The server cancels a request's context once ServeHTTP returns.
+	cancel()
+	close(labeler.release)
+	select {
+	case err := <-labeler.ctxErr:
+		if err != nil {
+			t.Errorf("labeling context error = %v, want it detached from the request", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("MIR-42 was not labeled after the response")
+	}
+}
+
+// stalledLabeler reports each call on started and blocks until release is
+// closed.
+type stalledLabeler struct {
+	started chan string
+	release chan struct{}
+}
+
+func (s stalledLabeler) EnsurePublicLabel(_ context.Context, identifier string) (linearapi.LabelResult, error) {
+	s.started <- identifier
+	<-s.release
+	return linearapi.LabelApplied, nil
+}
+
+func TestWebhookHandler_AsyncQueueFull(t *testing.T) {
+	labeler := stalledLabeler{started: make(chan string, 3), release: make(chan struct{})}
+	defer close(labeler.release)
+	handler := NewWebhookHandler("secret", "MIR", labeler)
+	handler.SetAsync(true)
+	handler.SetAsyncQueue(1, 1)
+
+	deliver := func(id string) int {
+		body := `{"commits":[{"message":"Fix ` + id + `"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+		req.Header.Set("X-GitHub-Event", "push")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// The first delivery stalls the only worker, the second waits in the
+	// queue, and the third has nowhere to go.
+	if code := deliver("MIR-1"); code != http.StatusAccepted {
+		t.Fatalf("first delivery status = %d, want %d", code, http.StatusAccepted)
+	}
+	select {
+	case <-labeler.started:
+	case <-time.After(time.Second):
+		t.Fatal("worker didn't pick up the first delivery")
+	}
+	if code := deliver("MIR-2"); code != http.StatusAccepted {
+		t.Errorf("queued delivery status = %d, want %d", code, http.StatusAccepted)
+	}
+	if code := deliver("MIR-3"); code != http.StatusServiceUnavailable {
+		t.Errorf("delivery to a full queue status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestWebhookHandler_MinNumbers(t *testing.T) {
+	mock := &mockLabeler{}
+	handler := NewWebhookHandler("secret", "MIR", mock)
+	handler.SetMinNumbers(MinNumbers{"MIR": 500})
+
+	body := `{"commits":[{"message":"MIR-499 and MIR-500, also MIR-1200"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+	req.Header.Set("X-GitHub-Event", "push")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if want := []string{"MIR-500", "MIR-1200"}; !slices.Equal(mock.called, want) {
+		t.Errorf("labeled = %v, want %v", mock.called, want)
+	}
+}
+
+func TestWebhookHandler_Verifier(t *testing.T) {
+	var requested []string
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/repos/org/app/pulls/5":
+			w.Write([]byte(`{"number":5,"title":"MIR-10 feature","body":"","head":{"ref":"feature"}}`))
+		case "/repos/org/app/pulls/7":
+			w.Write([]byte(`{"number":7,"title":"Tidy up","body":"No references","head":{"ref":"tidy"}}`))
+		case "/repos/org/app/commits/abc123":
+			w.Write([]byte(`{"sha":"abc123","commit":{"message":"Fix MIR-42"}}`))
+		case "/repos/org/app/branches/MIR-7-fix":
+			w.Write([]byte(`{"name":"MIR-7-fix"}`))
+		case "/repos/org/app/issues/comments/9":
+			w.Write([]byte(`{"id":9,"body":"Looks good"}`))
+		case "/repos/org/app/commits/bad":
+			http.Error(w, "Server Error", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer gh.Close()
+
+	tests := []struct {
+		name        string
+		event       string
+		body        string
+		wantLabeled []string
+	}{
+		{
+			name:        "pull request exists",
+			event:       "pull_request",
+			body:        `{"repository":{"full_name":"org/app"},"pull_request":{"number":5,"title":"MIR-10 feature"}}`,
+			wantLabeled: []string{"MIR-10"},
+		},
+		{
+			name:  "pull request missing",
+			event: "pull_request",
+			body:  `{"repository":{"full_name":"org/app"},"pull_request":{"number":6,"title":"MIR-10 feature"}}`,
+		},
+		{
+			name:  "real pull request with made-up title",
+			event: "pull_request",
+			body:  `{"repository":{"full_name":"org/app"},"pull_request":{"number":7,"title":"MIR-10 feature"}}`,
+		},
+		{
+			name:        "commits exist",
+			event:       "push",
+			body:        `{"repository":{"full_name":"org/app"},"commits":[{"id":"abc123","message":"Fix MIR-42"}]}`,
+			wantLabeled: []string{"MIR-42"},
+		},
+		{
+			name:        "real commit with made-up message",
+			event:       "push",
+			body:        `{"repository":{"full_name":"org/app"},"commits":[{"id":"abc123","message":"Fix MIR-99"}]}`,
+			wantLabeled: []string{"MIR-42"},
+		},
+		{
+			name:        "existing branch",
+			event:       "push",
+			body:        `{"ref":"refs/heads/MIR-7-fix","repository":{"full_name":"org/app"},"commits":[{"id":"abc123","message":"Fix MIR-42"}]}`,
+			wantLabeled: []string{"MIR-7", "MIR-42"},
+		},
+		{
+			name:        "made-up branch",
+			event:       "push",
+			body:        `{"ref":"refs/heads/MIR-8-fix","repository":{"full_name":"org/app"},"commits":[{"id":"abc123","message":"Fix MIR-42"}]}`,
+			wantLabeled: []string{"MIR-42"},
+		},
+		{
+			name:  "real comment with made-up body",
+			event: "issue_comment",
+			body:  `{"repository":{"full_name":"org/app"},"issue":{"number":3},"comment":{"id":9,"body":"Fixed by MIR-10"}}`,
+		},
+		{
+			name:  "one commit missing",
+			event: "push",
+			body:  `{"repository":{"full_name":"org/app"},"commits":[{"id":"abc123","message":"Fix MIR-42"},{"id":"def456","message":"Fix MIR-43"}]}`,
+		},
+		{
+			name:  "GitHub error",
+			event: "push",
+			body:  `{"repository":{"full_name":"org/app"},"commits":[{"id":"bad","message":"Fix MIR-42"}]}`,
+		},
+		{
+			name:  "no repository",
+			event: "pull_request",
+			body:  `{"pull_request":{"number":5,"title":"MIR-10 feature"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockLabeler{}
+			handler := NewWebhookHandler("secret", "MIR", mock)
+			verifier := NewVerifier("gh-token")
+			verifier.baseURL = gh.URL
+			handler.SetVerifier(verifier)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(tt.body))
+			req.Header.Set("X-Hub-Signature-256", sign("secret", tt.body))
+			req.Header.Set("X-GitHub-Event", tt.event)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rr.Code, http.StatusOK)
+			}
+			if !slices.Equal(mock.called, tt.wantLabeled) {
+				t.Errorf("labeled %v, want %v", mock.called, tt.wantLabeled)
+			}
+		})
+	}
+
+	// Deliveries that mention no issues don't cost an API call.
+	requested = nil
+	handler := NewWebhookHandler("secret", "MIR", &mockLabeler{})
+	verifier := NewVerifier("")
+	verifier.baseURL = gh.URL
+	handler.SetVerifier(verifier)
+	body := `{"repository":{"full_name":"org/app"},"pull_request":{"number":5,"title":"No references"}}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+	req.Header.Set("X-GitHub-Event", "pull_request")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if len(requested) != 0 {
+		t.Errorf("verifier requested %v for a delivery without references", requested)
+	}
+}
+
+func TestWebhookHandler_PublishDelay(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	deliver := func(handler *WebhookHandler, event, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+		req.Header.Set("X-GitHub-Event", event)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	// drain collects what was labeled within a few delays.
+	drain := func(labeled chanLabeler) []string {
+		var ids []string
+		timeout := time.After(4 * delay)
+		for {
+			select {
+			case id := <-labeled:
+				ids = append(ids, id)
+			case <-timeout:
+				return ids
+			}
+		}
+	}
+
+	t.Run("labels after the delay", func(t *testing.T) {
+		labeled := make(chanLabeler, 4)
+		handler := NewWebhookHandler("secret", "MIR", labeled)
+		handler.SetPublishDelay(delay)
+
+		start := time.Now()
+		rr := deliver(handler, "push", `{"commits":[{"message":"Fix MIR-42"}]}`)
+		if got := strings.TrimSpace(rr.Body.String()); got != `{"event":"push","matched":1,"processed":0,"scheduled":1}` {
+			t.Errorf("body = %s", got)
+		}
+		select {
+		case id := <-labeled:
+			if id != "MIR-42" {
+				t.Errorf("labeled %q, want MIR-42", id)
+			}
+			if elapsed := time.Since(start); elapsed < delay {
+				t.Errorf("labeled after %v, want at least %v", elapsed, delay)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("MIR-42 was not labeled after the delay")
+		}
+	})
+
+	t.Run("rapid deliveries label once", func(t *testing.T) {
+		labeled := make(chanLabeler, 4)
+		handler := NewWebhookHandler("secret", "MIR", labeled)
+		handler.SetPublishDelay(delay)
+
+		body := `{"commits":[{"message":"Fix MIR-42"}]}`
+		deliver(handler, "push", body)
+		deliver(handler, "push", body)
+		if got := drain(labeled); !slices.Equal(got, []string{"MIR-42"}) {
+			t.Errorf("labeled = %v, want [MIR-42] once", got)
+		}
+	})
+
+	t.Run("edit withdraws the reference", func(t *testing.T) {
+		labeled := make(chanLabeler, 4)
+		handler := NewWebhookHandler("secret", "MIR", labeled)
+		handler.SetPublishDelay(delay)
+
+		deliver(handler, "issue_comment", `{"action":"created","repository":{"full_name":"org/app"},"comment":{"id":7,"body":"See MIR-24, MIR-42"}}`)
+		deliver(handler, "issue_comment", `{"action":"edited","repository":{"full_name":"org/app"},"comment":{"id":7,"body":"See MIR-42"}}`)
+		if got := drain(labeled); !slices.Equal(got, []string{"MIR-42"}) {
+			t.Errorf("labeled = %v, want [MIR-42]", got)
+		}
+	})
+
+	t.Run("deleted subject withdraws the reference", func(t *testing.T) {
+		labeled := make(chanLabeler, 4)
+		handler := NewWebhookHandler("secret", "MIR", labeled)
+		handler.SetPublishDelay(delay)
+
+		comment := `"repository":{"full_name":"org/app"},"comment":{"id":7,"body":"See MIR-24"}`
+		deliver(handler, "issue_comment", `{"action":"created",`+comment+`}`)
+		deliver(handler, "push", `{"commits":[{"message":"Fix MIR-42"}]}`)
+		deliver(handler, "issue_comment", `{"action":"deleted",`+comment+`}`)
+		if got := drain(labeled); !slices.Equal(got, []string{"MIR-42"}) {
+			t.Errorf("labeled = %v, want [MIR-42]", got)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package github
+
+import (
+	"strings"
+
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+)
+
+// MinNumbers maps team keys to the lowest issue number that may be
+// published automatically, so teams can keep issues from before a cutover
+// private even when history references them. Teams without an entry have no
+// minimum.
+type MinNumbers map[string]int
+
+// Allows reports whether identifier is at or above its team's minimum.
+func (m MinNumbers) Allows(identifier string) bool {
+	teamKey, number, err := linearapi.ParseIdentifier(identifier)
+	if err != nil {
+		return false
+	}
+	return number >= m[strings.ToUpper(teamKey)]
+}
+
+// Filter returns the identifiers that m allows.
+func (m MinNumbers) Filter(identifiers []string) []string {
+	if len(m) == 0 {
+		return identifiers
+	}
+	var allowed []string
+	for _, id := range identifiers {
+		if m.Allows(id) {
+			allowed = append(allowed, id)
+		}
+	}
+	return allowed
+}
@@ -1,19 +1,112 @@
 package github
 
-import "regexp"
+import (
+	"regexp"
+	"strings"
+)
 
-var issuePattern = regexp.MustCompile(`\b([A-Z]+-\d+)\b`)
+// Identifiers may sit next to underscores, as in branch names like
+// fix_MIR-42_crash, so boundaries are letters and digits rather than \b
+// (which treats "_" as part of the word). RE2 has no lookahead, so the
+// trailing boundary is checked in findIdentifiers.
+const leadingBoundary = `(?:^|[^A-Za-z0-9])`
+
+// issueNumber matches Linear issue numbers, which start at 1. Numbers like
+// 0 or 007 never name an issue, so they aren't worth a lookup.
+const issueNumber = `[1-9]\d*`
+
+var issuePattern = regexp.MustCompile(leadingBoundary + `([A-Z]+-` + issueNumber + `)`)
 
 // ScanIdentifiers extracts all Linear issue identifiers (e.g. MIR-42) from text.
+// An identifier must not touch a letter or digit on either side, so MIR-42abc
+// and xMIR-42 are ignored while MIR-42_fix and MIR-42/title are matched.
 func ScanIdentifiers(text string) []string {
-	matches := issuePattern.FindAllString(text, -1)
-	seen := make(map[string]bool, len(matches))
-	var unique []string
-	for _, m := range matches {
-		if !seen[m] {
-			seen[m] = true
-			unique = append(unique, m)
+	return scanUnique(issuePattern, text)
+}
+
+// teamIssuePattern matches identifiers of the given teams. Unlike
+// issuePattern it allows keys containing digits (e.g. WEB2-3); that would
+// produce false positives like SHA256-1 when scanning for any team, but is
+// safe once the keys are known.
+func teamIssuePattern(teamKeys ...string) *regexp.Regexp {
+	quoted := make([]string, len(teamKeys))
+	for i, k := range teamKeys {
+		quoted[i] = regexp.QuoteMeta(strings.ToUpper(k))
+	}
+	return regexp.MustCompile(leadingBoundary + `((?:` + strings.Join(quoted, "|") + `)-` + issueNumber + `)`)
+}
+
+// ScanTeamIdentifiers extracts identifiers belonging to teamKey from text.
+func ScanTeamIdentifiers(text, teamKey string) []string {
+	return scanUnique(teamIssuePattern(teamKey), text)
+}
+
+// scanUnique returns the identifiers re finds in text, without repeats, in
+// order of first appearance. Webhook pushes can carry thousands of mentions,
+// so it dedupes straight from the match locations rather than building a
+// Match for each.
+func scanUnique(re *regexp.Regexp, text string) []string {
+	locs := re.FindAllStringSubmatchIndex(text, -1)
+	if len(locs) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(locs))
+	unique := make([]string, 0, len(locs))
+	for _, loc := range locs {
+		id, ok := identifierAt(text, loc)
+		if !ok {
+			continue
+		}
+		if _, dup := seen[id]; !dup {
+			seen[id] = struct{}{}
+			unique = append(unique, id)
 		}
 	}
+	if len(unique) == 0 {
+		return nil
+	}
 	return unique
 }
+
+// Match is an identifier reference and the byte offset where it starts.
+type Match struct {
+	Identifier string
+	Offset     int
+}
+
+// ScanIdentifiersWithPositions returns every identifier reference in text in
+// document order, including repeats.
+func ScanIdentifiersWithPositions(text string) []Match {
+	return findIdentifiers(issuePattern, text)
+}
+
+func findIdentifiers(re *regexp.Regexp, text string) []Match {
+	locs := re.FindAllStringSubmatchIndex(text, -1)
+	if len(locs) == 0 {
+		return nil
+	}
+	matches := make([]Match, 0, len(locs))
+	for _, loc := range locs {
+		if id, ok := identifierAt(text, loc); ok {
+			matches = append(matches, Match{Identifier: id, Offset: loc[2]})
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches
+}
+
+// identifierAt returns the identifier captured at loc, unless a letter or
+// digit follows it.
+func identifierAt(text string, loc []int) (string, bool) {
+	start, end := loc[2], loc[3]
+	if end < len(text) && isAlphanumeric(text[end]) {
+		return "", false
+	}
+	return text[start:end], true
+}
+
+func isAlphanumeric(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
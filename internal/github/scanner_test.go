@@ -1,7 +1,10 @@
 package github
 
 import (
+	"fmt"
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -41,11 +44,51 @@ func TestScanIdentifiers(t *testing.T) {
 			input: "See https://linear.app/miren/issue/MIR-42/some-title",
 			want:  []string{"MIR-42"},
 		},
+		{
+			name:  "number zero",
+			input: "MIR-0 is not an issue but MIR-1 is",
+			want:  []string{"MIR-1"},
+		},
+		{
+			name:  "leading zero",
+			input: "MIR-007",
+			want:  nil,
+		},
 		{
 			name:  "lowercase not matched",
 			input: "mir-42 should not match",
 			want:  nil,
 		},
+		{
+			name:  "inside markdown link",
+			input: "fixed in [#42](https://linear.app/miren/issue/MIR-42/title)",
+			want:  []string{"MIR-42"},
+		},
+		{
+			name:  "followed by underscore",
+			input: "branch MIR-42_fix-crash",
+			want:  []string{"MIR-42"},
+		},
+		{
+			name:  "preceded by underscore",
+			input: "branch fix_MIR-42",
+			want:  []string{"MIR-42"},
+		},
+		{
+			name:  "followed by letters",
+			input: "MIR-42abc is not a reference",
+			want:  nil,
+		},
+		{
+			name:  "preceded by lowercase letters",
+			input: "xMIR-42 is not a reference",
+			want:  nil,
+		},
+		{
+			name:  "adjacent references",
+			input: "MIR-1,MIR-2 MIR-3",
+			want:  []string{"MIR-1", "MIR-2", "MIR-3"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -56,3 +99,132 @@ func TestScanIdentifiers(t *testing.T) {
 		})
 	}
 }
+
+func TestScanTeamIdentifiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		teamKey string
+		input   string
+		want    []string
+	}{
+		{
+			name:    "key with digit",
+			teamKey: "WEB2",
+			input:   "Fixes WEB2-3 and WEB2-10",
+			want:    []string{"WEB2-3", "WEB2-10"},
+		},
+		{
+			name:    "other teams ignored",
+			teamKey: "WEB2",
+			input:   "WEB-3, MIR-4, SHA256-1",
+			want:    nil,
+		},
+		{
+			name:    "key embedded in longer word",
+			teamKey: "MIR",
+			input:   "XMIR-42 but MIR-7",
+			want:    []string{"MIR-7"},
+		},
+		{
+			name:    "underscore-separated slug",
+			teamKey: "MIR",
+			input:   "user/MIR-42_fix_MIR-7",
+			want:    []string{"MIR-42", "MIR-7"},
+		},
+		{
+			name:    "number zero",
+			teamKey: "MIR",
+			input:   "MIR-0, MIR-01, MIR-10",
+			want:    []string{"MIR-10"},
+		},
+		{
+			name:    "lowercase configured key",
+			teamKey: "mir",
+			input:   "MIR-42",
+			want:    []string{"MIR-42"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ScanTeamIdentifiers(tt.input, tt.teamKey)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ScanTeamIdentifiers(%q, %q) = %v, want %v", tt.input, tt.teamKey, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanIdentifiersWithPositions(t *testing.T) {
+	text := "Fixes MIR-42, see MIR-7 and MIR-42"
+	got := ScanIdentifiersWithPositions(text)
+	want := []Match{
+		{Identifier: "MIR-42", Offset: 6},
+		{Identifier: "MIR-7", Offset: 18},
+		{Identifier: "MIR-42", Offset: 28},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ScanIdentifiersWithPositions(%q) = %v, want %v", text, got, want)
+	}
+	for _, m := range got {
+		if text[m.Offset:m.Offset+len(m.Identifier)] != m.Identifier {
+			t.Errorf("offset %d does not point at %s", m.Offset, m.Identifier)
+		}
+	}
+
+	if got := ScanIdentifiersWithPositions("no references"); got != nil {
+		t.Errorf("expected nil, got %v", got)
+	}
+}
+
+// largeScanInput is a push-sized block of commit messages mentioning many
+// identifiers, with repeats, near misses, and identifiers of other teams.
+func largeScanInput() string {
+	var b strings.Builder
+	for i := range 5000 {
+		fmt.Fprintf(&b, "Fix MIR-%d crash in handler (see MIR-%d, WEB-%d)\n", i%700+1, i%50+1, i)
+		fmt.Fprintf(&b, "Bump SHA256-%d and MIR-%dabc, fix_MIR-%d_branch\n\n", i, i, i%900+1)
+	}
+	return b.String()
+}
+
+// scanUniqueReference is the straightforward version of scanUnique the
+// optimized one must agree with.
+func scanUniqueReference(re *regexp.Regexp, text string) []string {
+	var matches []string
+	for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[2], loc[3]
+		if end < len(text) && isAlphanumeric(text[end]) {
+			continue
+		}
+		matches = append(matches, text[start:end])
+	}
+	seen := make(map[string]bool)
+	var unique []string
+	for _, m := range matches {
+		if !seen[m] {
+			seen[m] = true
+			unique = append(unique, m)
+		}
+	}
+	return unique
+}
+
+func TestScanIdentifiersLargeInput(t *testing.T) {
+	input := largeScanInput()
+	if got, want := ScanIdentifiers(input), scanUniqueReference(issuePattern, input); !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanIdentifiers returned %d identifiers, reference %d", len(got), len(want))
+	}
+	re := teamIssuePattern("MIR")
+	if got, want := ScanTeamIdentifiers(input, "MIR"), scanUniqueReference(re, input); !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanTeamIdentifiers returned %d identifiers, reference %d", len(got), len(want))
+	}
+}
+
+func BenchmarkScanIdentifiers(b *testing.B) {
+	input := largeScanInput()
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for b.Loop() {
+		ScanIdentifiers(input)
+	}
+}
@@ -0,0 +1,22 @@
+package github
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ResolveToken returns the GitHub token to use: GITHUB_TOKEN if set,
+// otherwise whatever `gh auth token` prints, so a developer logged in with
+// the gh CLI needn't export one. It returns "" if neither is available;
+// callers then make unauthenticated requests.
+func ResolveToken() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	out, err := exec.Command("gh", "auth", "token").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
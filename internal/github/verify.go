@@ -0,0 +1,188 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Verifier checks deliveries against the GitHub API before they are acted
+// on: the commits, pull requests, issues, and comments a payload describes
+// must exist in the repository it names, and identifiers are then read from
+// their text as GitHub has it rather than as the payload says. A signed but
+// forged payload, as could be sent if the webhook secret leaked, then can't
+// publish issues by citing them from made-up commits or comments, or by
+// pairing made-up text with real ones.
+type Verifier struct {
+	baseURL string
+	token   string
+}
+
+// NewVerifier authenticates with token if it isn't empty. Unauthenticated
+// checks only work for public repos and share a small rate limit.
+func NewVerifier(token string) *Verifier {
+	return &Verifier{
+		baseURL: "https://api.github.com",
+		token:   token,
+	}
+}
+
+// deliverySubjects is the part of a webhook payload naming what it is about.
+type deliverySubjects struct {
+	Action     string `json:"action"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Commits []struct {
+		ID string `json:"id"`
+	} `json:"commits"`
+	PullRequest *struct {
+		Number int `json:"number"`
+	} `json:"pull_request"`
+	Issue *struct {
+		Number int `json:"number"`
+	} `json:"issue"`
+	Review *struct {
+		ID int64 `json:"id"`
+	} `json:"review"`
+	Comment *struct {
+		ID int64 `json:"id"`
+	} `json:"comment"`
+}
+
+// Verify fetches what the delivery describes from its repository and returns
+// the delivery with the text that may mention identifiers replaced by
+// GitHub's copy. ok is false if anything it describes doesn't exist, and for
+// deliveries it can't tie to anything checkable.
+func (v *Verifier) Verify(ctx context.Context, eventType string, body []byte) (verified []byte, ok bool, err error) {
+	var d deliverySubjects
+	if err := json.Unmarshal(body, &d); err != nil {
+		return nil, false, fmt.Errorf("decode delivery: %w", err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, false, fmt.Errorf("decode delivery: %w", err)
+	}
+	owner, repo, found := strings.Cut(d.Repository.FullName, "/")
+	if !found || owner == "" || repo == "" {
+		return nil, false, nil
+	}
+	repoURL := fmt.Sprintf("%s/repos/%s/%s", v.baseURL, url.PathEscape(owner), url.PathEscape(repo))
+
+	// replace sets payload[field] to the object at path, as GitHub has it.
+	replace := func(field, path string) (bool, error) {
+		var obj map[string]any
+		found, err := v.get(ctx, repoURL+path, &obj)
+		if found {
+			payload[field] = obj
+		}
+		return found, err
+	}
+
+	switch eventType {
+	case "push":
+		commits, _ := payload["commits"].([]any)
+		if len(commits) == 0 || len(commits) != len(d.Commits) {
+			return nil, false, nil
+		}
+		for i, c := range d.Commits {
+			var commit struct {
+				Commit struct {
+					Message string `json:"message"`
+				} `json:"commit"`
+			}
+			found, err := v.get(ctx, repoURL+"/commits/"+url.PathEscape(c.ID), &commit)
+			if err != nil || !found {
+				return nil, false, err
+			}
+			commits[i] = map[string]any{"id": c.ID, "message": commit.Commit.Message}
+		}
+		// A branch that was deleted since the push has no name to confirm,
+		// so it is left out rather than failing the commits.
+		if ref, _ := payload["ref"].(string); ref != "" {
+			branch := strings.TrimPrefix(ref, "refs/heads/")
+			found, err := v.get(ctx, repoURL+"/branches/"+url.PathEscape(branch), nil)
+			if err != nil {
+				return nil, false, err
+			}
+			if !found {
+				delete(payload, "ref")
+			}
+		}
+		ok = true
+	case "pull_request":
+		if d.PullRequest == nil {
+			return nil, false, nil
+		}
+		ok, err = replace("pull_request", fmt.Sprintf("/pulls/%d", d.PullRequest.Number))
+	case "pull_request_review":
+		if d.PullRequest == nil || d.Review == nil {
+			return nil, false, nil
+		}
+		ok, err = replace("review", fmt.Sprintf("/pulls/%d/reviews/%d", d.PullRequest.Number, d.Review.ID))
+	case "pull_request_review_comment":
+		if d.Comment == nil {
+			return nil, false, nil
+		}
+		ok, err = replace("comment", fmt.Sprintf("/pulls/comments/%d", d.Comment.ID))
+	case "issues":
+		if d.Issue == nil {
+			return nil, false, nil
+		}
+		ok, err = replace("issue", fmt.Sprintf("/issues/%d", d.Issue.Number))
+	case "issue_comment":
+		if d.Comment == nil {
+			return nil, false, nil
+		}
+		ok, err = replace("comment", fmt.Sprintf("/issues/comments/%d", d.Comment.ID))
+	default:
+		return nil, false, nil
+	}
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	verified, err = json.Marshal(payload)
+	if err != nil {
+		return nil, false, err
+	}
+	return verified, true, nil
+}
+
+// get decodes the object at url into into, unless into is nil, and reports
+// false if it doesn't exist.
+func (v *Verifier) get(ctx context.Context, url string, into any) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if v.token != "" {
+		req.Header.Set("Authorization", "Bearer "+v.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if into == nil {
+			return true, nil
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(into); err != nil {
+			return false, fmt.Errorf("decode %s: %w", url, err)
+		}
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("GitHub API %s: %s", resp.Status, body)
+	}
+}
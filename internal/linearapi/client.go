@@ -6,18 +6,33 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"miren.dev/linear-issue-bridge/internal/retry"
 )
 
 const defaultEndpoint = "https://api.linear.app/graphql"
 
+// defaultTimeout bounds each request made with the default HTTP client.
+const defaultTimeout = 10 * time.Second
+
 type Client struct {
-	apiKey     string
-	endpoint   string
-	httpClient *http.Client
+	apiKey           string
+	endpoint         string
+	httpClient       *http.Client
+	batchConcurrency int
+	includeSubTeams  bool
+	lookupFallback   bool
+	foldLabels       bool
+	publicLabel      string
+	retry            retry.Policy
+	limiter          rateLimiter
 }
 
 func NewClient(apiKey string) *Client {
@@ -25,8 +40,11 @@ func NewClient(apiKey string) *Client {
 		apiKey:   apiKey,
 		endpoint: defaultEndpoint,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: defaultTimeout,
 		},
+		batchConcurrency: defaultBatchConcurrency,
+		publicLabel:      DefaultPublicLabel,
+		retry:            retry.Default,
 	}
 }
 
@@ -35,16 +53,70 @@ func (c *Client) SetEndpoint(endpoint string) {
 	c.endpoint = endpoint
 }
 
-const issueByIdentifierQuery = `
-query IssueByIdentifier($teamKey: String!, $number: Float!) {
-  issues(
-    filter: {
-      team: { key: { eq: $teamKey } }
-      number: { eq: $number }
-    }
-    first: 1
-  ) {
-    nodes {
+// SetHTTPClient makes requests go through hc, for proxies, custom TLS,
+// timeouts, or instrumented transports. A nil hc restores the default
+// client, which times out after 10 seconds.
+func (c *Client) SetHTTPClient(hc *http.Client) {
+	if hc == nil {
+		hc = &http.Client{Timeout: defaultTimeout}
+	}
+	c.httpClient = hc
+}
+
+// SetIncludeSubTeams makes FetchIssue also look in sub-teams of the
+// identifier's team, for orgs whose issues move into child teams.
+func (c *Client) SetIncludeSubTeams(include bool) {
+	c.includeSubTeams = include
+}
+
+// SetLookupFallback makes FetchIssue, when the team and number filter finds
+// nothing, ask Linear for the issue by identifier directly before reporting
+// it missing. This guards against filter quirks (odd team keys, renamed
+// teams) turning real issues into 404s, at the cost of a second query for
+// identifiers that truly don't exist.
+func (c *Client) SetLookupFallback(enabled bool) {
+	c.lookupFallback = enabled
+}
+
+// SetCaseInsensitiveLabels makes label names match regardless of case, so
+// an issue labeled "Public" counts as public and label lookups find
+// "Public" when asked for "public". Labels are still applied by the ID
+// Linear returns.
+func (c *Client) SetCaseInsensitiveLabels(enabled bool) {
+	c.foldLabels = enabled
+}
+
+// SetPublicLabel changes the name of the label that publishes issues, for
+// teams that call it something like "published" or "external". Issues the
+// client fetches carry the name, so GateMode.IsPublic checks for it.
+func (c *Client) SetPublicLabel(name string) {
+	c.publicLabel = name
+}
+
+// PublicLabel returns the name of the label that publishes issues.
+func (c *Client) PublicLabel() string {
+	return c.publicLabel
+}
+
+// SetRetryPolicy controls how requests that fail with a network error, rate
+// limit, or server error are retried.
+func (c *Client) SetRetryPolicy(p retry.Policy) {
+	c.retry = p
+}
+
+// SetBatchConcurrency limits how many of the queries FetchIssues splits a
+// lookup into run at once. Running them serially is slow, but running them
+// all together trips Linear's rate limits.
+func (c *Client) SetBatchConcurrency(n int) {
+	c.batchConcurrency = max(n, 1)
+}
+
+// maxBatchSize caps how many issues FetchIssues asks for in one query.
+const maxBatchSize = 50
+
+const defaultBatchConcurrency = 4
+
+const issueFields = `
       id
       identifier
       title
@@ -69,18 +141,119 @@ query IssueByIdentifier($teamKey: String!, $number: Float!) {
         nodes {
           url
           title
+          metadata
         }
       }
+      project {
+        name
+        url
+        state
+      }
+      reactions {
+        emoji
+      }
+      relations {
+        nodes {
+          type
+          relatedIssue {
+            identifier
+          }
+        }
+      }
+      history {
+        nodes {
+          createdAt
+          fromState {
+            name
+          }
+          toState {
+            name
+          }
+          addedLabels {
+            name
+          }
+        }
+      }
+      # comments
+`
+
+const issueByIdentifierQuery = `
+query IssueByIdentifier($teamKey: String!, $number: Float!) {
+  issues(
+    filter: {
+      team: { key: { eq: $teamKey } }
+      number: { eq: $number }
     }
+    first: 1
+  ) {
+    nodes {` + issueFields + `    }
   }
 }
 `
 
+// issueInTeamTreeQuery is issueByIdentifierQuery widened to the team's
+// sub-teams. A parent and a sub-team can both have an issue with the same
+// number, so it asks for more than one and FetchIssue picks the exact match.
+const issueInTeamTreeQuery = `
+query IssueInTeamTree($teamKey: String!, $number: Float!) {
+  issues(
+    filter: {
+      team: {
+        or: [
+          { key: { eq: $teamKey } }
+          { parent: { key: { eq: $teamKey } } }
+        ]
+      }
+      number: { eq: $number }
+    }
+    first: 10
+  ) {
+    nodes {` + issueFields + `    }
+  }
+}
+`
+
+// issueByIDQuery looks an issue up by identifier; Linear's issue(id:)
+// accepts identifiers as well as UUIDs.
+const issueByIDQuery = `
+query IssueByID($id: String!) {
+  issue(id: $id) {` + issueFields + `  }
+}
+`
+
+const issuesByNumbersQuery = `
+query IssuesByNumbers($teamKey: String!, $numbers: [Float!]!, $first: Int!) {
+  issues(
+    filter: {
+      team: { key: { eq: $teamKey } }
+      number: { in: $numbers }
+    }
+    first: $first
+  ) {
+    nodes {` + issueFields + `    }
+  }
+}
+`
+
+const recentIssuesQuery = `
+query RecentIssues($filter: IssueFilter!, $first: Int!) {
+  issues(filter: $filter, first: $first, orderBy: updatedAt) {
+    nodes {` + issueFields + `    }
+  }
+}
+`
+
+// labelByNameQuery finds a label the team can use: its own, or one shared
+// by the whole workspace.
 const labelByNameQuery = `
-query LabelByName($labelName: String!) {
+query LabelByName($teamKey: String!, $labelName: String!) {
   issueLabels(
     filter: {
       name: { eq: $labelName }
+      or: [
+        { team: { key: { eq: $teamKey } } }
+        { team: { null: true } }
+      ]
     }
     first: 1
   ) {
@@ -92,6 +265,24 @@ query LabelByName($labelName: String!) {
 }
 `
 
+// labelByNameIgnoreCaseQuery is labelByNameQuery matching names regardless
+// of case.
+var labelByNameIgnoreCaseQuery = strings.Replace(labelByNameQuery, "eq:", "eqIgnoreCase:", 1)
+
+const labelsByNamesQuery = `
+query LabelsByNames($filter: IssueLabelFilter!, $first: Int!) {
+  issueLabels(
+    filter: $filter
+    first: $first
+  ) {
+    nodes {
+      id
+      name
+    }
+  }
+}
+`
+
 const addLabelMutation = `
 mutation AddLabel($issueID: String!, $labelID: String!) {
   issueAddLabel(id: $issueID, labelId: $labelID) {
@@ -100,6 +291,44 @@ mutation AddLabel($issueID: String!, $labelID: String!) {
 }
 `
 
+const removeLabelMutation = `
+mutation RemoveLabel($issueID: String!, $labelID: String!) {
+  issueRemoveLabel(id: $issueID, labelId: $labelID) {
+    success
+  }
+}
+`
+
+const createLabelMutation = `
+mutation CreateLabel($teamID: String!, $name: String!, $color: String!) {
+  issueLabelCreate(input: { teamId: $teamID, name: $name, color: $color }) {
+    success
+    issueLabel {
+      id
+    }
+  }
+}
+`
+
+const viewerQuery = `
+query Viewer {
+  viewer {
+    name
+  }
+}
+`
+
+const teamByKeyQuery = `
+query TeamByKey($teamKey: String!) {
+  teams(filter: { key: { eq: $teamKey } }, first: 1) {
+    nodes {
+      id
+      name
+    }
+  }
+}
+`
+
 type graphQLRequest struct {
 	Query     string         `json:"query"`
 	Variables map[string]any `json:"variables"`
@@ -141,10 +370,46 @@ type issueJSON struct {
 	} `json:"labels"`
 	Attachments struct {
 		Nodes []struct {
-			URL   string `json:"url"`
-			Title string `json:"title"`
+			URL      string `json:"url"`
+			Title    string `json:"title"`
+			Metadata struct {
+				Status string `json:"status"`
+			} `json:"metadata"`
 		} `json:"nodes"`
 	} `json:"attachments"`
+	Project *struct {
+		Name  string `json:"name"`
+		URL   string `json:"url"`
+		State string `json:"state"`
+	} `json:"project"`
+	Reactions []struct {
+		Emoji string `json:"emoji"`
+	} `json:"reactions"`
+	Relations struct {
+		Nodes []struct {
+			Type         string `json:"type"`
+			RelatedIssue struct {
+				Identifier string `json:"identifier"`
+			} `json:"relatedIssue"`
+		} `json:"nodes"`
+	} `json:"relations"`
+	History struct {
+		Nodes []historyJSON `json:"nodes"`
+	} `json:"history"`
+	Comments commentsJSON `json:"comments"`
+}
+
+type historyJSON struct {
+	CreatedAt time.Time `json:"createdAt"`
+	FromState *struct {
+		Name string `json:"name"`
+	} `json:"fromState"`
+	ToState *struct {
+		Name string `json:"name"`
+	} `json:"toState"`
+	AddedLabels []struct {
+		Name string `json:"name"`
+	} `json:"addedLabels"`
 }
 
 // ParseIdentifier splits "MIR-42" into ("MIR", 42).
@@ -171,27 +436,38 @@ func (c *Client) do(ctx context.Context, query string, variables map[string]any)
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(bodyBytes))
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", c.apiKey)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("execute request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBytes, err := io.ReadAll(resp.Body)
+	var respBytes []byte
+	err = c.retry.Do(ctx, func() (bool, error) {
+		if err := c.limiter.wait(ctx); err != nil {
+			return false, fmt.Errorf("wait for rate limit reset: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(bodyBytes))
+		if err != nil {
+			return false, fmt.Errorf("create request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", c.apiKey)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return ctx.Err() == nil && retry.Network(err), fmt.Errorf("execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		c.limiter.observe(resp.Header)
+
+		respBytes, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return true, fmt.Errorf("read response: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return retry.Status(resp.StatusCode), fmt.Errorf("linear API returned %d: %s", resp.StatusCode, string(respBytes))
+		}
+		return false, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("linear API returned %d: %s", resp.StatusCode, string(respBytes))
+		return nil, err
 	}
 
 	var gqlResp graphQLResponse
@@ -206,15 +482,27 @@ func (c *Client) do(ctx context.Context, query string, variables map[string]any)
 	return gqlResp.Data, nil
 }
 
-// FetchIssue retrieves an issue by its identifier (e.g. "MIR-42").
-// Returns nil, nil if the issue is not found.
+// FetchIssue retrieves an issue by its identifier (e.g. "MIR-42"), without
+// its comments. Returns nil, nil if the issue is not found.
 func (c *Client) FetchIssue(ctx context.Context, identifier string) (*Issue, error) {
+	return c.fetchIssue(ctx, identifier, false)
+}
+
+func (c *Client) fetchIssue(ctx context.Context, identifier string, comments bool) (*Issue, error) {
 	teamKey, number, err := ParseIdentifier(identifier)
 	if err != nil {
 		return nil, err
 	}
+	if number < 1 {
+		// Linear numbers issues from 1.
+		return nil, nil
+	}
 
-	data, err := c.do(ctx, issueByIdentifierQuery, map[string]any{
+	query := issueByIdentifierQuery
+	if c.includeSubTeams {
+		query = issueInTeamTreeQuery
+	}
+	data, err := c.do(ctx, issueQuery(query, comments), map[string]any{
 		"teamKey": teamKey,
 		"number":  float64(number),
 	})
@@ -227,17 +515,208 @@ func (c *Client) FetchIssue(ctx context.Context, identifier string) (*Issue, err
 		return nil, fmt.Errorf("decode issue data: %w", err)
 	}
 
-	if len(issueResp.Issues.Nodes) == 0 {
+	// Only an exact match will do: with sub-teams, the other nodes are
+	// different issues that merely share the number.
+	nodes := issueResp.Issues.Nodes
+	for i := range nodes {
+		if strings.EqualFold(nodes[i].Identifier, identifier) {
+			return c.toIssueWithComments(ctx, &nodes[i], comments)
+		}
+	}
+	if c.lookupFallback {
+		return c.fetchIssueByID(ctx, identifier, comments)
+	}
+	return nil, nil
+}
+
+// fetchIssueByID returns nil, nil if Linear reports no such issue.
+func (c *Client) fetchIssueByID(ctx context.Context, identifier string, comments bool) (*Issue, error) {
+	data, err := c.do(ctx, issueQuery(issueByIDQuery, comments), map[string]any{"id": identifier})
+	if err != nil {
+		if strings.Contains(err.Error(), "Entity not found") {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var resp struct {
+		Issue *issueJSON `json:"issue"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decode issue data: %w", err)
+	}
+	if resp.Issue == nil {
 		return nil, nil
 	}
+	return c.toIssueWithComments(ctx, resp.Issue, comments)
+}
+
+// FetchIssues retrieves several issues at once, keyed by identifier. It only
+// looks in each identifier's own team, so issues FetchIssue would find in a
+// sub-team or through the lookup fallback are absent from the result, as are
+// those that don't exist. Comments aren't fetched.
+func (c *Client) FetchIssues(ctx context.Context, identifiers []string) (map[string]*Issue, error) {
+	return c.fetchIssues(ctx, identifiers, false)
+}
+
+func (c *Client) fetchIssues(ctx context.Context, identifiers []string, comments bool) (map[string]*Issue, error) {
+	byTeam := make(map[string][]float64)
+	var teams []string
+	for _, id := range identifiers {
+		teamKey, number, err := ParseIdentifier(id)
+		if err != nil {
+			return nil, err
+		}
+		if number < 1 {
+			continue
+		}
+		if _, ok := byTeam[teamKey]; !ok {
+			teams = append(teams, teamKey)
+		}
+		if slices.Contains(byTeam[teamKey], float64(number)) {
+			continue
+		}
+		byTeam[teamKey] = append(byTeam[teamKey], float64(number))
+	}
+
+	var (
+		mu     sync.Mutex
+		issues = make(map[string]*Issue, len(identifiers))
+		tasks  []func(context.Context) error
+	)
+	for _, teamKey := range teams {
+		for numbers := range slices.Chunk(byTeam[teamKey], maxBatchSize) {
+			tasks = append(tasks, func(ctx context.Context) error {
+				found, err := c.fetchIssueBatch(ctx, teamKey, numbers, comments)
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				maps.Copy(issues, found)
+				mu.Unlock()
+				return nil
+			})
+		}
+	}
+
+	if err := runLimited(ctx, c.batchConcurrency, tasks); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+func (c *Client) fetchIssueBatch(ctx context.Context, teamKey string, numbers []float64, comments bool) (map[string]*Issue, error) {
+	data, err := c.do(ctx, issueQuery(issuesByNumbersQuery, comments), map[string]any{
+		"teamKey": teamKey,
+		"numbers": numbers,
+		"first":   len(numbers),
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	return issueResp.Issues.Nodes[0].toIssue(), nil
+	var issueResp issuesResponse
+	if err := json.Unmarshal(data, &issueResp); err != nil {
+		return nil, fmt.Errorf("decode issue data: %w", err)
+	}
+
+	found := make(map[string]*Issue, len(issueResp.Issues.Nodes))
+	for i := range issueResp.Issues.Nodes {
+		issue, err := c.toIssueWithComments(ctx, &issueResp.Issues.Nodes[i], comments)
+		if err != nil {
+			return nil, err
+		}
+		found[issue.Identifier] = issue
+	}
+	return found, nil
 }
 
-// FetchLabelByName returns the UUID of a label by name within a team.
-// Returns "", nil if the label is not found.
-func (c *Client) FetchLabelByName(ctx context.Context, _, name string) (string, error) {
-	data, err := c.do(ctx, labelByNameQuery, map[string]any{
+// FetchPublicIssues returns up to first of the team's public issues, most
+// recently updated first. In allowlist mode Linear filters on the public
+// label; in denylist mode the most recent issues are fetched. Either way
+// private ones are dropped, so fewer than first may come back.
+func (c *Client) FetchPublicIssues(ctx context.Context, teamKey string, gate GateMode, first int) ([]*Issue, error) {
+	filter := map[string]any{
+		"team": map[string]any{"key": map[string]any{"eq": teamKey}},
+	}
+	if gate != GateDenylist {
+		filter["labels"] = map[string]any{"some": map[string]any{"name": map[string]any{c.labelComparator(): c.publicLabel}}}
+	}
+
+	data, err := c.do(ctx, recentIssuesQuery, map[string]any{
+		"filter": filter,
+		"first":  first,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var issueResp issuesResponse
+	if err := json.Unmarshal(data, &issueResp); err != nil {
+		return nil, fmt.Errorf("decode issue data: %w", err)
+	}
+
+	var issues []*Issue
+	for i := range issueResp.Issues.Nodes {
+		if issue := c.toIssue(&issueResp.Issues.Nodes[i]); gate.IsPublic(issue) {
+			issues = append(issues, issue)
+		}
+	}
+	slices.SortStableFunc(issues, func(a, b *Issue) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+	return issues, nil
+}
+
+// runLimited runs tasks with at most limit in flight, returning the first
+// error. The remaining tasks are cancelled once one fails.
+func runLimited(ctx context.Context, limit int, tasks []func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, limit)
+	for _, task := range tasks {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := task(ctx); err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// FetchLabelByName returns the UUID of a label by name within a team,
+// including workspace labels. Returns "", nil if the label is not found.
+func (c *Client) FetchLabelByName(ctx context.Context, teamKey, name string) (string, error) {
+	query := labelByNameQuery
+	if c.foldLabels {
+		query = labelByNameIgnoreCaseQuery
+	}
+	data, err := c.do(ctx, query, map[string]any{
+		"teamKey":   teamKey,
 		"labelName": name,
 	})
 	if err != nil {
@@ -262,13 +741,197 @@ func (c *Client) FetchLabelByName(ctx context.Context, _, name string) (string,
 	return resp.IssueLabels.Nodes[0].ID, nil
 }
 
+// FetchLabelsByNames resolves several label names in one query, returning a
+// map from the names asked for to UUIDs. Names without a matching label are
+// absent from the map.
+func (c *Client) FetchLabelsByNames(ctx context.Context, _ string, names []string) (map[string]string, error) {
+	// Linear has no case-insensitive "in", so folded lookups ask for each
+	// name with the same comparator FetchLabelByName uses.
+	filter := map[string]any{"name": map[string]any{"in": names}}
+	if c.foldLabels {
+		or := make([]any, len(names))
+		for i, name := range names {
+			or[i] = map[string]any{"name": map[string]any{c.labelComparator(): name}}
+		}
+		filter = map[string]any{"or": or}
+	}
+	data, err := c.do(ctx, labelsByNamesQuery, map[string]any{
+		"filter": filter,
+		"first":  len(names),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		IssueLabels struct {
+			Nodes []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"nodes"`
+		} `json:"issueLabels"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decode label data: %w", err)
+	}
+
+	ids := make(map[string]string, len(resp.IssueLabels.Nodes))
+	for _, name := range names {
+		for _, n := range resp.IssueLabels.Nodes {
+			if n.Name == name {
+				ids[name] = n.ID
+				break
+			}
+			if c.foldLabels && strings.EqualFold(n.Name, name) {
+				ids[name] = n.ID
+			}
+		}
+	}
+	return ids, nil
+}
+
+// FetchViewer returns the name of the user the API key belongs to, which
+// confirms the key is valid.
+func (c *Client) FetchViewer(ctx context.Context) (string, error) {
+	data, err := c.do(ctx, viewerQuery, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Viewer struct {
+			Name string `json:"name"`
+		} `json:"viewer"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("decode viewer data: %w", err)
+	}
+	return resp.Viewer.Name, nil
+}
+
+// FetchTeamName returns the name of the team with key teamKey.
+// Returns "", nil if there is no such team.
+func (c *Client) FetchTeamName(ctx context.Context, teamKey string) (string, error) {
+	team, err := c.fetchTeam(ctx, teamKey)
+	return team.Name, err
+}
+
+type teamJSON struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// fetchTeam returns the team with key teamKey, or a zero team if there is
+// none.
+func (c *Client) fetchTeam(ctx context.Context, teamKey string) (teamJSON, error) {
+	data, err := c.do(ctx, teamByKeyQuery, map[string]any{"teamKey": teamKey})
+	if err != nil {
+		return teamJSON{}, err
+	}
+
+	var resp struct {
+		Teams struct {
+			Nodes []teamJSON `json:"nodes"`
+		} `json:"teams"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return teamJSON{}, fmt.Errorf("decode team data: %w", err)
+	}
+	if len(resp.Teams.Nodes) == 0 {
+		return teamJSON{}, nil
+	}
+	return resp.Teams.Nodes[0], nil
+}
+
+// CreateLabel creates a label in the team with key teamKey and returns its
+// UUID. color is a hex color such as "#4cb782".
+func (c *Client) CreateLabel(ctx context.Context, teamKey, name, color string) (string, error) {
+	team, err := c.fetchTeam(ctx, teamKey)
+	if err != nil {
+		return "", err
+	}
+	if team.ID == "" {
+		return "", fmt.Errorf("team %s not found", teamKey)
+	}
+
+	data, err := c.do(ctx, createLabelMutation, map[string]any{
+		"teamID": team.ID,
+		"name":   name,
+		"color":  color,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		IssueLabelCreate struct {
+			Success    bool `json:"success"`
+			IssueLabel *struct {
+				ID string `json:"id"`
+			} `json:"issueLabel"`
+		} `json:"issueLabelCreate"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("decode issueLabelCreate response: %w", err)
+	}
+	if !resp.IssueLabelCreate.Success || resp.IssueLabelCreate.IssueLabel == nil {
+		return "", fmt.Errorf("linear API: issueLabelCreate did not succeed")
+	}
+	return resp.IssueLabelCreate.IssueLabel.ID, nil
+}
+
 // AddLabel appends a label to an issue.
 func (c *Client) AddLabel(ctx context.Context, issueID, labelID string) error {
-	_, err := c.do(ctx, addLabelMutation, map[string]any{
+	return c.mutate(ctx, "issueAddLabel", addLabelMutation, map[string]any{
+		"issueID": issueID,
+		"labelID": labelID,
+	})
+}
+
+func (c *Client) RemoveLabel(ctx context.Context, issueID, labelID string) error {
+	return c.mutate(ctx, "issueRemoveLabel", removeLabelMutation, map[string]any{
 		"issueID": issueID,
 		"labelID": labelID,
 	})
-	return err
+}
+
+// mutate runs a mutation whose payload, under field, reports success. Linear
+// can answer success: false without any GraphQL error.
+func (c *Client) mutate(ctx context.Context, field, mutation string, variables map[string]any) error {
+	data, err := c.do(ctx, mutation, variables)
+	if err != nil {
+		return err
+	}
+
+	var resp map[string]struct {
+		Success bool `json:"success"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("decode %s response: %w", field, err)
+	}
+	if !resp[field].Success {
+		return fmt.Errorf("linear API: %s did not succeed", field)
+	}
+	return nil
+}
+
+// toIssue converts j, carrying over how the client matches label names.
+func (c *Client) toIssue(j *issueJSON) *Issue {
+	issue := j.toIssue()
+	issue.foldLabels = c.foldLabels
+	if c.publicLabel != DefaultPublicLabel {
+		issue.publicLabel = c.publicLabel
+	}
+	issue.History = issue.publicHistory()
+	return issue
+}
+
+// labelComparator is the GraphQL string comparison for label names.
+func (c *Client) labelComparator() string {
+	if c.foldLabels {
+		return "eqIgnoreCase"
+	}
+	return "eq"
 }
 
 func (j *issueJSON) toIssue() *Issue {
@@ -278,7 +941,43 @@ func (j *issueJSON) toIssue() *Issue {
 	}
 	attachments := make([]Attachment, len(j.Attachments.Nodes))
 	for i, n := range j.Attachments.Nodes {
-		attachments[i] = Attachment{URL: n.URL, Title: n.Title}
+		attachments[i] = Attachment{URL: n.URL, Title: n.Title, Status: n.Metadata.Status}
+	}
+	var project *Project
+	if j.Project != nil {
+		project = &Project{Name: j.Project.Name, URL: j.Project.URL, State: j.Project.State}
+	}
+	var reactions []Reaction
+	counts := make(map[string]int)
+	for _, r := range j.Reactions {
+		if _, ok := counts[r.Emoji]; !ok {
+			reactions = append(reactions, Reaction{Emoji: r.Emoji})
+		}
+		counts[r.Emoji]++
+	}
+	for i := range reactions {
+		reactions[i].Count = counts[reactions[i].Emoji]
+	}
+	var history []HistoryEvent
+	for _, n := range j.History.Nodes {
+		if ev, ok := n.toEvent(); ok {
+			history = append(history, ev)
+		}
+	}
+	slices.SortFunc(history, func(a, b HistoryEvent) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	var comments []Comment
+	for _, n := range j.Comments.Nodes {
+		comments = append(comments, n.toComment())
+	}
+	sortComments(comments)
+	var duplicateOf string
+	for _, n := range j.Relations.Nodes {
+		if n.Type == "duplicate" {
+			duplicateOf = n.RelatedIssue.Identifier
+			break
+		}
 	}
 	return &Issue{
 		ID:          j.ID,
@@ -289,8 +988,30 @@ func (j *issueJSON) toIssue() *Issue {
 		Priority:    j.Priority,
 		Labels:      labels,
 		Attachments: attachments,
+		Reactions:   reactions,
+		History:     history,
+		Comments:    comments,
+		Project:     project,
+		DuplicateOf: duplicateOf,
 		URL:         j.URL,
 		CreatedAt:   j.CreatedAt,
 		UpdatedAt:   j.UpdatedAt,
 	}
 }
+
+// toEvent keeps only state transitions and label additions; every other kind
+// of history entry is dropped. Issue.publicHistory then drops labels that
+// shouldn't be shown.
+func (h *historyJSON) toEvent() (HistoryEvent, bool) {
+	ev := HistoryEvent{CreatedAt: h.CreatedAt}
+	if h.ToState != nil {
+		ev.ToState = h.ToState.Name
+		if h.FromState != nil {
+			ev.FromState = h.FromState.Name
+		}
+	}
+	for _, l := range h.AddedLabels {
+		ev.AddedLabels = append(ev.AddedLabels, l.Name)
+	}
+	return ev, ev.ToState != "" || len(ev.AddedLabels) > 0
+}
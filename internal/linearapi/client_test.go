@@ -3,9 +3,17 @@ package linearapi
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/retry"
 )
 
 func TestParseIdentifier(t *testing.T) {
@@ -18,6 +26,7 @@ func TestParseIdentifier(t *testing.T) {
 		{"MIR-42", "MIR", 42, false},
 		{"ABC-1", "ABC", 1, false},
 		{"MIR-0", "MIR", 0, false},
+		{"WEB2-3", "WEB2", 3, false},
 		{"NOSPACE", "", 0, true},
 		{"MIR-abc", "", 0, true},
 	}
@@ -69,10 +78,42 @@ func TestFetchIssue(t *testing.T) {
 							},
 							"attachments": map[string]any{
 								"nodes": []map[string]any{
-									{"url": "https://github.com/mirendev/linear-issue-bridge/pull/1", "title": "feat: add PR links"},
+									{"url": "https://github.com/mirendev/linear-issue-bridge/pull/1", "title": "feat: add PR links", "metadata": map[string]any{"status": "merged"}},
 									{"url": "https://linear.app/some-other-link", "title": "Other"},
 								},
 							},
+							"project": map[string]any{
+								"name":  "Public Roadmap",
+								"url":   "https://linear.app/miren/project/public-roadmap",
+								"state": "started",
+							},
+							"reactions": []map[string]any{
+								{"emoji": "+1"},
+								{"emoji": "heart"},
+								{"emoji": "+1"},
+							},
+							"relations": map[string]any{
+								"nodes": []map[string]any{
+									{"type": "related", "relatedIssue": map[string]any{"identifier": "MIR-7"}},
+									{"type": "duplicate", "relatedIssue": map[string]any{"identifier": "MIR-1"}},
+								},
+							},
+							"history": map[string]any{
+								"nodes": []map[string]any{
+									{
+										"createdAt": "2025-01-15T12:00:00.000Z",
+										"fromState": map[string]any{"name": "Todo"},
+										"toState":   map[string]any{"name": "In Progress"},
+									},
+									{
+										"createdAt":   "2025-01-15T11:00:00.000Z",
+										"addedLabels": []map[string]any{{"name": "bug"}, {"name": "needs-triage"}},
+									},
+									{
+										"createdAt": "2025-01-15T10:30:00.000Z",
+									},
+								},
+							},
 						},
 					},
 				},
@@ -125,6 +166,27 @@ func TestFetchIssue(t *testing.T) {
 	if prs[0].Title != "feat: add PR links" {
 		t.Errorf("PR title = %q, want %q", prs[0].Title, "feat: add PR links")
 	}
+	if prs[0].Status != "merged" {
+		t.Errorf("PR status = %q, want %q", prs[0].Status, "merged")
+	}
+	wantProject := &Project{Name: "Public Roadmap", URL: "https://linear.app/miren/project/public-roadmap", State: "started"}
+	if !reflect.DeepEqual(issue.Project, wantProject) {
+		t.Errorf("Project = %+v, want %+v", issue.Project, wantProject)
+	}
+	if issue.DuplicateOf != "MIR-1" {
+		t.Errorf("DuplicateOf = %q, want %q", issue.DuplicateOf, "MIR-1")
+	}
+	wantReactions := []Reaction{{Emoji: "+1", Count: 2}, {Emoji: "heart", Count: 1}}
+	if !reflect.DeepEqual(issue.Reactions, wantReactions) {
+		t.Errorf("Reactions = %v, want %v", issue.Reactions, wantReactions)
+	}
+	wantHistory := []HistoryEvent{
+		{CreatedAt: time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC), AddedLabels: []string{"bug"}},
+		{CreatedAt: time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC), FromState: "Todo", ToState: "In Progress"},
+	}
+	if !reflect.DeepEqual(issue.History, wantHistory) {
+		t.Errorf("History = %+v, want %+v", issue.History, wantHistory)
+	}
 }
 
 func TestFetchIssueNotFound(t *testing.T) {
@@ -153,6 +215,187 @@ func TestFetchIssueNotFound(t *testing.T) {
 	}
 }
 
+func TestFetchIssueNumberZero(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("MIR-0 should not be looked up")
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+
+	issue, err := client.FetchIssue(context.Background(), "MIR-0")
+	if err != nil || issue != nil {
+		t.Errorf("FetchIssue(MIR-0) = %v, %v; want nil, nil", issue, err)
+	}
+}
+
+func TestFetchIssueWithoutProject(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := map[string]any{
+			"data": map[string]any{
+				"issues": map[string]any{
+					"nodes": []map[string]any{
+						{"id": "issue-uuid-1", "identifier": "MIR-42", "project": nil},
+					},
+				},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+
+	issue, err := client.FetchIssue(context.Background(), "MIR-42")
+	if err != nil {
+		t.Fatalf("FetchIssue: %v", err)
+	}
+	if issue.Project != nil {
+		t.Errorf("Project = %+v, want nil", issue.Project)
+	}
+}
+
+func TestFetchIssueLookupFallback(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		fallback  any // response to IssueByID: an issue, or an error message
+		wantFound bool
+		wantCalls int
+	}{
+		{"finds issue", true, map[string]any{"id": "issue-uuid-1", "identifier": "MIR-42", "title": "Found"}, true, 2},
+		{"not found", true, "Entity not found: Issue", false, 2},
+		{"disabled", false, nil, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				var req graphQLRequest
+				json.NewDecoder(r.Body).Decode(&req)
+
+				var resp any
+				switch {
+				case strings.Contains(req.Query, "IssueByIdentifier"):
+					resp = map[string]any{"data": map[string]any{"issues": map[string]any{"nodes": []any{}}}}
+				case strings.Contains(req.Query, "IssueByID"):
+					if req.Variables["id"] != "MIR-42" {
+						t.Errorf("id = %v, want MIR-42", req.Variables["id"])
+					}
+					if msg, ok := tt.fallback.(string); ok {
+						resp = map[string]any{"data": nil, "errors": []map[string]any{{"message": msg}}}
+					} else {
+						resp = map[string]any{"data": map[string]any{"issue": tt.fallback}}
+					}
+				default:
+					t.Fatalf("unexpected query: %s", req.Query)
+				}
+				json.NewEncoder(w).Encode(resp)
+			}))
+			defer srv.Close()
+
+			client := NewClient("test-key")
+			client.SetEndpoint(srv.URL)
+			client.SetLookupFallback(tt.enabled)
+
+			issue, err := client.FetchIssue(context.Background(), "MIR-42")
+			if err != nil {
+				t.Fatalf("FetchIssue: %v", err)
+			}
+			if (issue != nil) != tt.wantFound {
+				t.Errorf("issue = %+v, want found %v", issue, tt.wantFound)
+			}
+			if issue != nil && issue.Title != "Found" {
+				t.Errorf("Title = %q, want %q", issue.Title, "Found")
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("requests = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFetchIssueComments(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		queries = append(queries, req.Query)
+
+		var data map[string]any
+		if strings.Contains(req.Query, "IssueComments") {
+			if req.Variables["id"] != "issue-uuid-1" || req.Variables["after"] != "cursor-1" {
+				t.Errorf("comments page variables = %v", req.Variables)
+			}
+			data = map[string]any{"issue": map[string]any{"comments": map[string]any{
+				"nodes": []map[string]any{
+					{"body": "Second", "createdAt": "2025-01-16T12:00:00Z", "botActor": map[string]any{"name": "GitHub"}},
+				},
+				"pageInfo": map[string]any{"hasNextPage": false},
+			}}}
+		} else {
+			data = map[string]any{"issues": map[string]any{"nodes": []map[string]any{{
+				"id": "issue-uuid-1", "identifier": "MIR-42",
+				"comments": map[string]any{
+					"nodes": []map[string]any{
+						{"body": "First *comment*", "createdAt": "2025-01-15T12:00:00Z", "user": map[string]any{"displayName": "alice"}},
+					},
+					"pageInfo": map[string]any{"hasNextPage": true, "endCursor": "cursor-1"},
+				},
+			}}}}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+
+	issue, err := client.WithComments().FetchIssue(context.Background(), "MIR-42")
+	if err != nil {
+		t.Fatalf("FetchIssue: %v", err)
+	}
+	want := []Comment{
+		{Author: "alice", Body: "First *comment*", CreatedAt: time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)},
+		{Author: "GitHub", Body: "Second", CreatedAt: time.Date(2025, 1, 16, 12, 0, 0, 0, time.UTC)},
+	}
+	if !reflect.DeepEqual(issue.Comments, want) {
+		t.Errorf("Comments = %+v, want %+v", issue.Comments, want)
+	}
+	if len(queries) != 2 || !strings.Contains(queries[0], "comments(first: 50)") {
+		t.Errorf("queries = %d, want the issue with its first comments then one more page", len(queries))
+	}
+}
+
+func TestFetchIssueCommentsDisabled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if strings.Contains(req.Query, "comments(") {
+			t.Error("query asks for comments without WithComments")
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"issues": map[string]any{
+			"nodes": []map[string]any{{"id": "issue-uuid-1", "identifier": "MIR-42"}},
+		}}})
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+
+	issue, err := client.FetchIssue(context.Background(), "MIR-42")
+	if err != nil {
+		t.Fatalf("FetchIssue: %v", err)
+	}
+	if issue.Comments != nil {
+		t.Errorf("Comments = %+v, want none", issue.Comments)
+	}
+}
+
 func TestFetchIssueGraphQLError(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := map[string]any{
@@ -173,8 +416,494 @@ func TestFetchIssueGraphQLError(t *testing.T) {
 	}
 }
 
+func TestFetchIssueRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    retry.Policy
+		failures  []int // statuses of the responses before a success
+		wantCalls int
+		wantErr   bool
+	}{
+		{"recovers", retry.Policy{MaxRetries: 2, Base: time.Millisecond}, []int{http.StatusServiceUnavailable}, 2, false},
+		{"fails twice then succeeds", retry.Policy{MaxRetries: 3, Base: time.Millisecond}, []int{http.StatusBadGateway, http.StatusTooManyRequests}, 3, false},
+		{"retries exhausted", retry.Policy{MaxRetries: 1, Base: time.Millisecond}, []int{http.StatusGatewayTimeout, http.StatusInternalServerError}, 2, true},
+		{"not retryable", retry.Policy{MaxRetries: 2, Base: time.Millisecond}, []int{http.StatusNotImplemented}, 1, true},
+		{"disabled", retry.Policy{}, []int{http.StatusServiceUnavailable}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls <= len(tt.failures) {
+					http.Error(w, "failed", tt.failures[calls-1])
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]any{
+					"data": map[string]any{
+						"issues": map[string]any{
+							"nodes": []map[string]any{{"id": "issue-uuid-1", "identifier": "MIR-42"}},
+						},
+					},
+				})
+			}))
+			defer srv.Close()
+
+			client := NewClient("test-key")
+			client.SetEndpoint(srv.URL)
+			client.SetRetryPolicy(tt.policy)
+
+			_, err := client.FetchIssue(context.Background(), "MIR-42")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FetchIssue error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("requests = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// recordingTransport counts requests before passing them on.
+type recordingTransport struct {
+	requests int
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestSetHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{
+				"issues": map[string]any{
+					"nodes": []map[string]any{{"id": "issue-uuid-1", "identifier": "MIR-42"}},
+				},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	rt := &recordingTransport{}
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	client.SetHTTPClient(&http.Client{Transport: rt})
+
+	if _, err := client.FetchIssue(context.Background(), "MIR-42"); err != nil {
+		t.Fatalf("FetchIssue: %v", err)
+	}
+	if rt.requests != 1 {
+		t.Errorf("requests through the supplied client = %d, want 1", rt.requests)
+	}
+
+	client.SetHTTPClient(nil)
+	if client.httpClient.Timeout != defaultTimeout {
+		t.Errorf("nil client should restore the default, got timeout %v", client.httpClient.Timeout)
+	}
+}
+
+func TestRateLimitWaitsForReset(t *testing.T) {
+	const wait = 150 * time.Millisecond
+	var reset time.Time
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			reset = time.Now().Add(wait)
+			w.Header().Set("X-RateLimit-Requests-Limit", "1500")
+			w.Header().Set("X-RateLimit-Requests-Remaining", "0")
+			w.Header().Set("X-RateLimit-Requests-Reset", strconv.FormatInt(reset.UnixMilli(), 10))
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+			return
+		}
+		if now := time.Now(); now.Before(reset.Truncate(time.Millisecond)) {
+			t.Errorf("retried %v before the reset", reset.Sub(now))
+		}
+		w.Header().Set("X-RateLimit-Requests-Limit", "1500")
+		w.Header().Set("X-RateLimit-Requests-Remaining", "1499")
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{
+				"issues": map[string]any{
+					"nodes": []map[string]any{{"id": "issue-uuid-1", "identifier": "MIR-42"}},
+				},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	client.SetRetryPolicy(retry.Policy{MaxRetries: 2, Base: time.Millisecond})
+	if _, ok := client.RateLimit(); ok {
+		t.Error("RateLimit should report nothing before the first response")
+	}
+
+	start := time.Now()
+	if _, err := client.FetchIssue(context.Background(), "MIR-42"); err != nil {
+		t.Fatalf("FetchIssue: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < wait-10*time.Millisecond {
+		t.Errorf("FetchIssue took %v, want it to wait about %v for the reset", elapsed, wait)
+	}
+	if calls != 2 {
+		t.Errorf("requests = %d, want 2", calls)
+	}
+	if rl, ok := client.RateLimit(); !ok || rl.Limit != 1500 || rl.Remaining != 1499 {
+		t.Errorf("RateLimit = %+v, %t; want limit 1500, remaining 1499", rl, ok)
+	}
+}
+
+func TestRateLimitWaitBoundedByContext(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-RateLimit-Requests-Remaining", "0")
+		w.Header().Set("X-RateLimit-Requests-Reset", strconv.FormatInt(time.Now().Add(time.Hour).UnixMilli(), 10))
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	client.SetRetryPolicy(retry.Policy{MaxRetries: 2, Base: time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.FetchIssue(ctx, "MIR-42"); err == nil {
+		t.Fatal("expected error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("FetchIssue took %v; the wait should end with the context", elapsed)
+	}
+	if calls != 1 {
+		t.Errorf("requests = %d, want 1", calls)
+	}
+}
+
+func TestFetchIssueIncludeSubTeams(t *testing.T) {
+	var gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		gotQuery = req.Query
+
+		resp := map[string]any{
+			"data": map[string]any{
+				"issues": map[string]any{
+					"nodes": []map[string]any{
+						{"id": "uuid-sub", "identifier": "SUB-42", "title": "Other sub-team issue"},
+						{"id": "uuid-mir", "identifier": "MIR-42", "title": "Moved to sub-team"},
+					},
+				},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	client.SetIncludeSubTeams(true)
+
+	issue, err := client.FetchIssue(context.Background(), "MIR-42")
+	if err != nil {
+		t.Fatalf("FetchIssue: %v", err)
+	}
+	if !strings.Contains(gotQuery, "parent: { key: { eq: $teamKey } }") {
+		t.Errorf("query missing sub-team filter:\n%s", gotQuery)
+	}
+	if issue == nil || issue.ID != "uuid-mir" {
+		t.Errorf("issue = %+v, want the exact MIR-42 match", issue)
+	}
+}
+
+func TestFetchIssueIncludeSubTeamsNoExactMatch(t *testing.T) {
+	for _, fallback := range []bool{false, true} {
+		var queries []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req graphQLRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			queries = append(queries, req.Query)
+			w.Header().Set("Content-Type", "application/json")
+			if strings.Contains(req.Query, "IssueByID") {
+				fmt.Fprint(w, `{"errors":[{"message":"Entity not found: Issue"}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"data":{"issues":{"nodes":[{"id":"uuid-sub","identifier":"SUB-42","title":"Other sub-team issue"}]}}}`)
+		}))
+
+		client := NewClient("test-key")
+		client.SetEndpoint(srv.URL)
+		client.SetIncludeSubTeams(true)
+		client.SetLookupFallback(fallback)
+
+		issue, err := client.FetchIssue(context.Background(), "MIR-42")
+		srv.Close()
+		if err != nil {
+			t.Fatalf("fallback=%t: FetchIssue: %v", fallback, err)
+		}
+		if issue != nil {
+			t.Errorf("fallback=%t: issue = %+v, want nil rather than SUB-42", fallback, issue)
+		}
+		wantQueries := 1
+		if fallback {
+			wantQueries = 2
+		}
+		if len(queries) != wantQueries {
+			t.Errorf("fallback=%t: %d queries, want %d", fallback, len(queries), wantQueries)
+		}
+	}
+}
+
+func TestAddLabelUnsuccessful(t *testing.T) {
+	for _, success := range []bool{true, false} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"data":{"issueAddLabel":{"success":%t}}}`, success)
+		}))
+
+		client := NewClient("test-key")
+		client.SetEndpoint(srv.URL)
+
+		err := client.AddLabel(context.Background(), "issue-uuid-1", "label-uuid-1")
+		if success && err != nil {
+			t.Errorf("AddLabel with success:true: %v", err)
+		}
+		if !success && err == nil {
+			t.Error("expected error when mutation reports success:false")
+		}
+		srv.Close()
+	}
+}
+
+func TestFetchIssues(t *testing.T) {
+	var gotVars []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		gotVars = append(gotVars, req.Variables)
+
+		var nodes []map[string]any
+		for _, n := range req.Variables["numbers"].([]any) {
+			if n.(float64) == 999 {
+				continue
+			}
+			nodes = append(nodes, map[string]any{
+				"id":         fmt.Sprintf("uuid-%v", n),
+				"identifier": fmt.Sprintf("%s-%v", req.Variables["teamKey"], n),
+				"title":      "Batch",
+			})
+		}
+		resp := map[string]any{
+			"data": map[string]any{
+				"issues": map[string]any{"nodes": nodes},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+
+	issues, err := client.FetchIssues(context.Background(), []string{"MIR-1", "ABC-2", "MIR-999", "MIR-1"})
+	if err != nil {
+		t.Fatalf("FetchIssues: %v", err)
+	}
+	if len(gotVars) != 2 {
+		t.Fatalf("sent %d queries, want one per team", len(gotVars))
+	}
+	if len(issues) != 2 || issues["MIR-1"] == nil || issues["ABC-2"] == nil {
+		t.Errorf("issues = %v, want MIR-1 and ABC-2", issues)
+	}
+	if _, ok := issues["MIR-999"]; ok {
+		t.Error("missing issue should be absent from result")
+	}
+}
+
+func TestFetchIssuesConcurrencyLimit(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"issues":{"nodes":[]}}}`)
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	client.SetBatchConcurrency(2)
+
+	// One sub-query per team key.
+	ids := []string{"AA-1", "BB-1", "CC-1", "DD-1", "EE-1", "FF-1"}
+	if _, err := client.FetchIssues(context.Background(), ids); err != nil {
+		t.Fatalf("FetchIssues: %v", err)
+	}
+	if got := maxInFlight.Load(); got > 2 {
+		t.Errorf("max in-flight sub-queries = %d, want at most 2", got)
+	}
+}
+
+func TestFetchPublicIssues(t *testing.T) {
+	tests := []struct {
+		name      string
+		gate      GateMode
+		wantLabel bool
+		wantIDs   []string
+	}{
+		{"allowlist", GateAllowlist, true, []string{"MIR-2", "MIR-1"}},
+		{"denylist", GateDenylist, false, []string{"MIR-2", "MIR-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req graphQLRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				filter := req.Variables["filter"].(map[string]any)
+				if _, ok := filter["labels"]; ok != tt.wantLabel {
+					t.Errorf("label filter present = %v, want %v", ok, tt.wantLabel)
+				}
+
+				json.NewEncoder(w).Encode(map[string]any{
+					"data": map[string]any{
+						"issues": map[string]any{
+							"nodes": []map[string]any{
+								{"identifier": "MIR-1", "updatedAt": "2025-01-15T10:00:00.000Z", "labels": map[string]any{"nodes": []map[string]any{{"name": "public"}}}},
+								{"identifier": "MIR-2", "updatedAt": "2025-01-16T10:00:00.000Z", "labels": map[string]any{"nodes": []map[string]any{{"name": "public"}}}},
+								{"identifier": "MIR-3", "updatedAt": "2025-01-14T10:00:00.000Z", "labels": map[string]any{"nodes": []map[string]any{{"name": "public"}, {"name": "private"}}}},
+							},
+						},
+					},
+				})
+			}))
+			defer srv.Close()
+
+			client := NewClient("test-key")
+			client.SetEndpoint(srv.URL)
+
+			issues, err := client.FetchPublicIssues(context.Background(), "MIR", tt.gate, 10)
+			if err != nil {
+				t.Fatalf("FetchPublicIssues: %v", err)
+			}
+			var ids []string
+			for _, issue := range issues {
+				ids = append(ids, issue.Identifier)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("identifiers = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestCaseInsensitiveLabels(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		queries = append(queries, req.Query)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(req.Query, "LabelByName") {
+			fmt.Fprint(w, `{"data":{"issueLabels":{"nodes":[{"id":"label-uuid-public","name":"Public"}]}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"issues":{"nodes":[{"id":"i1","identifier":"MIR-42","labels":{"nodes":[{"id":"label-uuid-public","name":"Public"}]}}]}}}`)
+	}))
+	defer srv.Close()
+
+	for _, fold := range []bool{false, true} {
+		queries = nil
+		client := NewClient("test-key")
+		client.SetEndpoint(srv.URL)
+		client.SetCaseInsensitiveLabels(fold)
+
+		issue, err := client.FetchIssue(context.Background(), "MIR-42")
+		if err != nil {
+			t.Fatalf("FetchIssue: %v", err)
+		}
+		if got := GateAllowlist.IsPublic(issue); got != fold {
+			t.Errorf("fold=%t: issue labeled Public IsPublic = %t, want %t", fold, got, fold)
+		}
+
+		id, err := client.FetchLabelByName(context.Background(), "MIR", "public")
+		if err != nil || id != "label-uuid-public" {
+			t.Errorf("FetchLabelByName = %q, %v; want label-uuid-public", id, err)
+		}
+		if got := strings.Contains(queries[1], "eqIgnoreCase"); got != fold {
+			t.Errorf("fold=%t: label query uses eqIgnoreCase = %t", fold, got)
+		}
+	}
+}
+
+func TestPublicLabel(t *testing.T) {
+	var filters []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if f, ok := req.Variables["filter"]; ok {
+			filters = append(filters, f)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"issues":{"nodes":[
+			{"id":"i1","identifier":"MIR-1","labels":{"nodes":[{"id":"l1","name":"published"}]}},
+			{"id":"i2","identifier":"MIR-2","labels":{"nodes":[{"id":"l2","name":"public"}]}}
+		]}}}`)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		label      string // passed to SetPublicLabel if set
+		wantPublic []string
+	}{
+		{"", []string{"MIR-2"}},
+		{"published", []string{"MIR-1"}},
+	}
+	for _, tt := range tests {
+		filters = nil
+		client := NewClient("test-key")
+		client.SetEndpoint(srv.URL)
+		if tt.label != "" {
+			client.SetPublicLabel(tt.label)
+		}
+
+		issues, err := client.FetchPublicIssues(context.Background(), "MIR", GateAllowlist, 10)
+		if err != nil {
+			t.Fatalf("FetchPublicIssues: %v", err)
+		}
+		var got []string
+		for _, issue := range issues {
+			got = append(got, issue.Identifier)
+		}
+		if !reflect.DeepEqual(got, tt.wantPublic) {
+			t.Errorf("label %q: public issues = %v, want %v", tt.label, got, tt.wantPublic)
+		}
+		wantName := map[string]any{"eq": client.PublicLabel()}
+		if name := filters[0].(map[string]any)["labels"].(map[string]any)["some"].(map[string]any)["name"]; !reflect.DeepEqual(name, wantName) {
+			t.Errorf("label %q: filter on label name %v, want %v", tt.label, name, wantName)
+		}
+	}
+}
+
 func TestFetchLabelByName(t *testing.T) {
+	var gotTeam any
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		gotTeam = req.Variables["teamKey"]
 		resp := map[string]any{
 			"data": map[string]any{
 				"issueLabels": map[string]any{
@@ -199,6 +928,9 @@ func TestFetchLabelByName(t *testing.T) {
 	if id != "label-uuid-public" {
 		t.Errorf("ID = %q, want %q", id, "label-uuid-public")
 	}
+	if gotTeam != "MIR" {
+		t.Errorf("teamKey = %v, want MIR", gotTeam)
+	}
 }
 
 func TestFetchLabelByNameNotFound(t *testing.T) {
@@ -227,6 +959,81 @@ func TestFetchLabelByNameNotFound(t *testing.T) {
 	}
 }
 
+func TestFetchLabelsByNames(t *testing.T) {
+	var gotNames []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		filter, _ := req.Variables["filter"].(map[string]any)
+		name, _ := filter["name"].(map[string]any)
+		gotNames, _ = name["in"].([]any)
+
+		resp := map[string]any{
+			"data": map[string]any{
+				"issueLabels": map[string]any{
+					"nodes": []map[string]any{
+						{"id": "label-uuid-public", "name": "public"},
+						{"id": "label-uuid-nonpublic", "name": "nonpublic"},
+					},
+				},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+
+	ids, err := client.FetchLabelsByNames(context.Background(), "MIR", []string{"public", "nonpublic", "missing"})
+	if err != nil {
+		t.Fatalf("FetchLabelsByNames: %v", err)
+	}
+	if len(gotNames) != 3 {
+		t.Errorf("sent labelNames %v, want all three names in one query", gotNames)
+	}
+	want := map[string]string{"public": "label-uuid-public", "nonpublic": "label-uuid-nonpublic"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	if _, ok := ids["missing"]; ok {
+		t.Error("missing label should be absent from the map")
+	}
+}
+
+func TestFetchLabelsByNamesCaseInsensitive(t *testing.T) {
+	var gotFilter map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		gotFilter, _ = req.Variables["filter"].(map[string]any)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"issueLabels":{"nodes":[{"id":"label-uuid-public","name":"Public"},{"id":"label-uuid-nonpublic","name":"nonpublic"}]}}}`)
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	client.SetCaseInsensitiveLabels(true)
+
+	ids, err := client.FetchLabelsByNames(context.Background(), "MIR", []string{"public", "NonPublic"})
+	if err != nil {
+		t.Fatalf("FetchLabelsByNames: %v", err)
+	}
+	want := map[string]string{"public": "label-uuid-public", "NonPublic": "label-uuid-nonpublic"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	wantFilter := map[string]any{"or": []any{
+		map[string]any{"name": map[string]any{"eqIgnoreCase": "public"}},
+		map[string]any{"name": map[string]any{"eqIgnoreCase": "NonPublic"}},
+	}}
+	if !reflect.DeepEqual(gotFilter, wantFilter) {
+		t.Errorf("filter = %v, want %v", gotFilter, wantFilter)
+	}
+}
+
 func TestAddLabel(t *testing.T) {
 	var gotQuery string
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -257,3 +1064,29 @@ func TestAddLabel(t *testing.T) {
 		t.Fatal("expected a GraphQL query to be sent")
 	}
 }
+
+func TestRemoveLabel(t *testing.T) {
+	for _, success := range []bool{true, false} {
+		var got graphQLRequest
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&got)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"data":{"issueRemoveLabel":{"success":%t}}}`, success)
+		}))
+
+		client := NewClient("test-key")
+		client.SetEndpoint(srv.URL)
+
+		err := client.RemoveLabel(context.Background(), "issue-uuid-1", "label-uuid-1")
+		if success && err != nil {
+			t.Errorf("RemoveLabel with success:true: %v", err)
+		}
+		if !success && err == nil {
+			t.Error("expected error when mutation reports success:false")
+		}
+		if !strings.Contains(got.Query, "issueRemoveLabel") || got.Variables["issueID"] != "issue-uuid-1" || got.Variables["labelID"] != "label-uuid-1" {
+			t.Errorf("request = %+v, want issueRemoveLabel for issue-uuid-1 and label-uuid-1", got)
+		}
+		srv.Close()
+	}
+}
@@ -0,0 +1,157 @@
+package linearapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// commentsMarker is a GraphQL comment in issueFields that issueQuery swaps
+// for commentFields when comments are wanted, so issues are fetched with
+// their first page of comments in the same query.
+const commentsMarker = "      # comments\n"
+
+const commentFields = `
+      comments(first: 50) {` + commentConnectionFields + `      }
+`
+
+const commentConnectionFields = `
+        nodes {
+          body
+          createdAt
+          user {
+            displayName
+          }
+          botActor {
+            name
+          }
+        }
+        pageInfo {
+          hasNextPage
+          endCursor
+        }
+`
+
+const issueCommentsQuery = `
+query IssueComments($id: String!, $after: String!) {
+  issue(id: $id) {
+    comments(first: 100, after: $after) {` + commentConnectionFields + `    }
+  }
+}
+`
+
+// maxCommentPages bounds how many further pages of comments are fetched for
+// one issue, so a runaway thread can't stall page loads.
+const maxCommentPages = 10
+
+type commentsJSON struct {
+	Nodes    []commentJSON `json:"nodes"`
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+}
+
+type commentJSON struct {
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+	User      *struct {
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	BotActor *struct {
+		Name string `json:"name"`
+	} `json:"botActor"`
+}
+
+func (j *commentJSON) toComment() Comment {
+	c := Comment{Body: j.Body, CreatedAt: j.CreatedAt}
+	switch {
+	case j.User != nil:
+		c.Author = j.User.DisplayName
+	case j.BotActor != nil:
+		c.Author = j.BotActor.Name
+	}
+	return c
+}
+
+// CommentFetcher looks issues up through a Client along with their
+// comments, so pages can show them below the description. Only the page
+// cache should use it: everything else reads labels and would pay for the
+// comment queries for nothing.
+type CommentFetcher struct {
+	client *Client
+}
+
+// WithComments returns a fetcher of issues with their comments that shares
+// c's settings and rate limit.
+func (c *Client) WithComments() *CommentFetcher {
+	return &CommentFetcher{client: c}
+}
+
+// FetchIssue is Client.FetchIssue, also fetching the issue's comments.
+func (f *CommentFetcher) FetchIssue(ctx context.Context, identifier string) (*Issue, error) {
+	return f.client.fetchIssue(ctx, identifier, true)
+}
+
+// FetchIssues is Client.FetchIssues, also fetching the issues' comments.
+func (f *CommentFetcher) FetchIssues(ctx context.Context, identifiers []string) (map[string]*Issue, error) {
+	return f.client.fetchIssues(ctx, identifiers, true)
+}
+
+// issueQuery adds comment fields to an issue query when comments are
+// wanted.
+func issueQuery(query string, comments bool) string {
+	if !comments {
+		return query
+	}
+	return strings.Replace(query, commentsMarker, commentFields, 1)
+}
+
+// toIssueWithComments converts j like toIssue, fetching any comments beyond
+// the first page when comments are wanted.
+func (c *Client) toIssueWithComments(ctx context.Context, j *issueJSON, comments bool) (*Issue, error) {
+	issue := c.toIssue(j)
+	if !comments || !j.Comments.PageInfo.HasNextPage {
+		return issue, nil
+	}
+
+	cursor := j.Comments.PageInfo.EndCursor
+	for page := 0; ; page++ {
+		if page == maxCommentPages {
+			slog.Warn("issue has too many comments, showing the first ones", "identifier", issue.Identifier, "comments", len(issue.Comments))
+			break
+		}
+		data, err := c.do(ctx, issueCommentsQuery, map[string]any{"id": issue.ID, "after": cursor})
+		if err != nil {
+			return nil, fmt.Errorf("fetch comments: %w", err)
+		}
+		var resp struct {
+			Issue struct {
+				Comments commentsJSON `json:"comments"`
+			} `json:"issue"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, fmt.Errorf("decode comments: %w", err)
+		}
+		for _, n := range resp.Issue.Comments.Nodes {
+			issue.Comments = append(issue.Comments, n.toComment())
+		}
+		info := resp.Issue.Comments.PageInfo
+		if !info.HasNextPage {
+			break
+		}
+		cursor = info.EndCursor
+	}
+	sortComments(issue.Comments)
+	return issue, nil
+}
+
+func sortComments(comments []Comment) {
+	slices.SortStableFunc(comments, func(a, b Comment) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+}
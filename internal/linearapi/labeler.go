@@ -4,64 +4,280 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 )
 
-type PublicLabeler struct {
+// DefaultPublicLabel is the label that publishes issues unless
+// Client.SetPublicLabel names another.
+const DefaultPublicLabel = "public"
+
+// DefaultLabelColor is the color of labels LabelResolver creates.
+const DefaultLabelColor = "#4cb782"
+
+// LabelResolver looks up a label's ID on first use and shares the result
+// with every caller, so a process resolves each label once. A label that
+// doesn't exist yet, as in a fresh team, is created. Failed lookups aren't
+// remembered: the next caller tries again.
+type LabelResolver struct {
 	client  *Client
 	teamKey string
+	name    string
+	color   string
 
-	labelOnce sync.Once
-	labelID   string
-	labelErr  error
+	// sem is held while resolving, so concurrent callers share one lookup.
+	// It is a channel rather than a mutex so waiters can give up when their
+	// context ends instead of waiting out another caller's slow lookup.
+	sem chan struct{}
+	id  string
 }
 
-func NewPublicLabeler(client *Client, teamKey string) *PublicLabeler {
-	return &PublicLabeler{
+func NewLabelResolver(client *Client, teamKey, name string) *LabelResolver {
+	return &LabelResolver{
 		client:  client,
 		teamKey: teamKey,
+		name:    name,
+		color:   DefaultLabelColor,
+		sem:     make(chan struct{}, 1),
 	}
 }
 
-func (l *PublicLabeler) EnsurePublicLabel(ctx context.Context, identifier string) error {
+// SetColor sets the color the label is created with if it doesn't exist.
+func (r *LabelResolver) SetColor(color string) {
+	r.color = color
+}
+
+func (r *LabelResolver) LabelID(ctx context.Context) (string, error) {
+	select {
+	case r.sem <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-r.sem }()
+	if r.id != "" {
+		return r.id, nil
+	}
+
+	id, err := r.client.FetchLabelByName(ctx, r.teamKey, r.name)
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		if id, err = r.client.CreateLabel(ctx, r.teamKey, r.name, r.color); err != nil {
+			return "", fmt.Errorf("label %q not found in team %s, and creating it failed: %w", r.name, r.teamKey, err)
+		}
+		slog.Info("created missing label", "label", r.name, "team", r.teamKey, "color", r.color)
+	}
+	r.id = id
+	return id, nil
+}
+
+type PublicLabeler struct {
+	client *Client
+	label  *LabelResolver
+	teams  map[string]*LabelResolver // other teams' public labels, by key
+
+	// inFlight serializes labeling per identifier, so concurrent
+	// deliveries mentioning the same issue don't both add the label.
+	inFlight       keyedMutex
+	skipInProgress bool
+}
+
+func NewPublicLabeler(client *Client, teamKey string) *PublicLabeler {
+	return &PublicLabeler{
+		client: client,
+		label:  NewLabelResolver(client, teamKey, client.PublicLabel()),
+	}
+}
+
+// SetLabelColor sets the color the public label is created with if it
+// doesn't exist.
+func (l *PublicLabeler) SetLabelColor(color string) {
+	l.label.SetColor(color)
+}
+
+// SetLabelResolver shares r's public label lookup with other users of it.
+func (l *PublicLabeler) SetLabelResolver(r *LabelResolver) {
+	l.label = r
+}
+
+// SetTeamKeys makes the labeler label issues of each team in keys with that
+// team's public label, since Linear won't add one team's label to another
+// team's issue. Other issues get the label the labeler was created for. Call
+// it after SetLabelColor or SetLabelResolver, whose color it copies.
+func (l *PublicLabeler) SetTeamKeys(keys ...string) {
+	l.teams = make(map[string]*LabelResolver, len(keys))
+	for _, key := range keys {
+		key = strings.ToUpper(key)
+		if key == strings.ToUpper(l.label.teamKey) {
+			continue
+		}
+		r := NewLabelResolver(l.client, key, l.label.name)
+		r.SetColor(l.label.color)
+		l.teams[key] = r
+	}
+}
+
+// labelFor returns the public label lookup for identifier's team.
+func (l *PublicLabeler) labelFor(identifier string) *LabelResolver {
+	teamKey, _, _ := strings.Cut(identifier, "-")
+	if r, ok := l.teams[strings.ToUpper(teamKey)]; ok {
+		return r
+	}
+	return l.label
+}
+
+// SetSkipInProgress makes EnsurePublicLabel return LabelInProgress instead of
+// waiting when another call is already labeling the same identifier.
+func (l *PublicLabeler) SetSkipInProgress(skip bool) {
+	l.skipInProgress = skip
+}
+
+// LabelResult is the action EnsurePublicLabel took.
+type LabelResult string
+
+const (
+	LabelApplied       LabelResult = "applied"        // the public label was added
+	LabelAlreadyPublic LabelResult = "already_public" // the issue already had it
+	LabelSkipped       LabelResult = "skipped"        // the issue is labeled nonpublic or private
+	LabelNotFound      LabelResult = "not_found"      // no such issue
+	LabelInProgress    LabelResult = "in_progress"    // another call is labeling it
+)
+
+// EnsurePublicLabel adds the public label to identifier's issue unless it
+// already has it or is labeled nonpublic, private or confidential. Calls for the same identifier run
+// one at a time; later ones wait, or with SetSkipInProgress return
+// LabelInProgress.
+func (l *PublicLabeler) EnsurePublicLabel(ctx context.Context, identifier string) (LabelResult, error) {
+	if l.skipInProgress {
+		if !l.inFlight.TryLock(identifier) {
+			slog.Info("issue is already being labeled, skipping", "identifier", identifier)
+			return LabelInProgress, nil
+		}
+	} else if err := l.inFlight.Lock(ctx, identifier); err != nil {
+		return "", err
+	}
+	defer l.inFlight.Unlock(identifier)
+
 	issue, err := l.client.FetchIssue(ctx, identifier)
 	if err != nil {
-		return fmt.Errorf("fetch issue %s: %w", identifier, err)
+		return "", fmt.Errorf("fetch issue %s: %w", identifier, err)
 	}
 	if issue == nil {
 		slog.Info("issue not found, skipping", "identifier", identifier)
-		return nil
+		return LabelNotFound, nil
 	}
 
-	if issue.HasLabel("nonpublic") {
-		slog.Info("issue has nonpublic label, skipping", "identifier", identifier)
-		return nil
+	if issue.HasLabel("nonpublic") || slices.ContainsFunc(privateLabels, issue.HasLabel) {
+		slog.Info("issue is labeled nonpublic or private, skipping", "identifier", identifier)
+		return LabelSkipped, nil
 	}
 
-	if issue.HasLabel("public") {
+	if issue.HasLabel(issue.PublicLabel()) {
 		slog.Info("issue already has public label", "identifier", identifier)
-		return nil
+		return LabelAlreadyPublic, nil
 	}
 
-	labelID, err := l.resolveLabelID(ctx)
+	labelID, err := l.labelFor(identifier).LabelID(ctx)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if err := l.client.AddLabel(ctx, issue.ID, labelID); err != nil {
-		return fmt.Errorf("add label to %s: %w", identifier, err)
+		return "", fmt.Errorf("add label to %s: %w", identifier, err)
 	}
 
 	slog.Info("applied public label", "identifier", identifier)
-	return nil
+	return LabelApplied, nil
 }
 
-func (l *PublicLabeler) resolveLabelID(ctx context.Context) (string, error) {
-	l.labelOnce.Do(func() {
-		l.labelID, l.labelErr = l.client.FetchLabelByName(ctx, l.teamKey, "public")
-		if l.labelErr == nil && l.labelID == "" {
-			l.labelErr = fmt.Errorf("label %q not found in team %s", "public", l.teamKey)
+// RemovePublicLabel takes identifier's issue off the public site.
+func (l *PublicLabeler) RemovePublicLabel(ctx context.Context, identifier string) error {
+	issue, err := l.client.FetchIssue(ctx, identifier)
+	if err != nil {
+		return fmt.Errorf("fetch issue %s: %w", identifier, err)
+	}
+	if issue == nil {
+		slog.Info("issue not found, skipping", "identifier", identifier)
+		return nil
+	}
+
+	if label, ok := issue.Label(issue.PublicLabel()); ok {
+		if err := l.client.RemoveLabel(ctx, issue.ID, label.ID); err != nil {
+			return fmt.Errorf("remove label from %s: %w", identifier, err)
 		}
-	})
-	return l.labelID, l.labelErr
+		slog.Info("removed public label", "identifier", identifier)
+		return nil
+	}
+
+	slog.Info("issue has no public label", "identifier", identifier)
+	return nil
+}
+
+// keyedMutex is a set of mutexes created on demand, one per key. Entries are
+// dropped once nobody holds or waits for them.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sem  chan struct{}
+	refs int
+}
+
+func (m *keyedMutex) acquire(key string) *keyLock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.locks == nil {
+		m.locks = make(map[string]*keyLock)
+	}
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyLock{sem: make(chan struct{}, 1)}
+		m.locks[key] = l
+	}
+	l.refs++
+	return l
+}
+
+func (m *keyedMutex) release(key string, l *keyLock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l.refs--; l.refs == 0 {
+		delete(m.locks, key)
+	}
+}
+
+// Lock waits until key is free or ctx is done.
+func (m *keyedMutex) Lock(ctx context.Context, key string) error {
+	l := m.acquire(key)
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		m.release(key, l)
+		return ctx.Err()
+	}
+}
+
+// TryLock takes key if it is free and reports whether it did.
+func (m *keyedMutex) TryLock(key string) bool {
+	l := m.acquire(key)
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	default:
+		m.release(key, l)
+		return false
+	}
+}
+
+func (m *keyedMutex) Unlock(key string) {
+	m.mu.Lock()
+	l := m.locks[key]
+	m.mu.Unlock()
+	<-l.sem
+	m.release(key, l)
 }
@@ -3,10 +3,15 @@ package linearapi
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestPublicLabeler_IssueNotFound(t *testing.T) {
@@ -27,10 +32,13 @@ func TestPublicLabeler_IssueNotFound(t *testing.T) {
 	client.SetEndpoint(srv.URL)
 	labeler := NewPublicLabeler(client, "MIR")
 
-	err := labeler.EnsurePublicLabel(context.Background(), "MIR-999")
+	result, err := labeler.EnsurePublicLabel(context.Background(), "MIR-999")
 	if err != nil {
 		t.Fatalf("expected no error for missing issue, got: %v", err)
 	}
+	if result != LabelNotFound {
+		t.Errorf("result = %q, want %q", result, LabelNotFound)
+	}
 }
 
 func TestPublicLabeler_AlreadyLabeled(t *testing.T) {
@@ -66,10 +74,13 @@ func TestPublicLabeler_AlreadyLabeled(t *testing.T) {
 	client.SetEndpoint(srv.URL)
 	labeler := NewPublicLabeler(client, "MIR")
 
-	err := labeler.EnsurePublicLabel(context.Background(), "MIR-42")
+	result, err := labeler.EnsurePublicLabel(context.Background(), "MIR-42")
 	if err != nil {
 		t.Fatalf("expected no error for already-labeled issue, got: %v", err)
 	}
+	if result != LabelAlreadyPublic {
+		t.Errorf("result = %q, want %q", result, LabelAlreadyPublic)
+	}
 }
 
 func TestPublicLabeler_NonpublicLabel(t *testing.T) {
@@ -105,10 +116,38 @@ func TestPublicLabeler_NonpublicLabel(t *testing.T) {
 	client.SetEndpoint(srv.URL)
 	labeler := NewPublicLabeler(client, "MIR")
 
-	err := labeler.EnsurePublicLabel(context.Background(), "MIR-42")
+	result, err := labeler.EnsurePublicLabel(context.Background(), "MIR-42")
 	if err != nil {
 		t.Fatalf("expected no error for nonpublic issue, got: %v", err)
 	}
+	if result != LabelSkipped {
+		t.Errorf("result = %q, want %q", result, LabelSkipped)
+	}
+}
+
+func TestPublicLabeler_PrivateLabel(t *testing.T) {
+	for _, label := range []string{"private", "confidential"} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req graphQLRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if !strings.Contains(req.Query, "IssueByIdentifier") {
+				t.Errorf("unexpected query for a %s issue: %s", label, req.Query)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"data":{"issues":{"nodes":[{"id":"issue-uuid-1","identifier":"MIR-42","labels":{"nodes":[{"id":"l-1","name":%q}]}}]}}}`, label)
+		}))
+
+		client := NewClient("test-key")
+		client.SetEndpoint(srv.URL)
+		result, err := NewPublicLabeler(client, "MIR").EnsurePublicLabel(context.Background(), "MIR-42")
+		srv.Close()
+		if err != nil {
+			t.Fatalf("%s: EnsurePublicLabel: %v", label, err)
+		}
+		if result != LabelSkipped {
+			t.Errorf("%s: result = %q, want %q", label, result, LabelSkipped)
+		}
+	}
 }
 
 func TestPublicLabeler_AppliesLabel(t *testing.T) {
@@ -178,16 +217,114 @@ func TestPublicLabeler_AppliesLabel(t *testing.T) {
 	client.SetEndpoint(srv.URL)
 	labeler := NewPublicLabeler(client, "MIR")
 
-	err := labeler.EnsurePublicLabel(context.Background(), "MIR-42")
+	result, err := labeler.EnsurePublicLabel(context.Background(), "MIR-42")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if result != LabelApplied {
+		t.Errorf("result = %q, want %q", result, LabelApplied)
+	}
 
 	if callCount != 3 {
 		t.Errorf("expected 3 API calls (fetch issue, fetch label, add label), got %d", callCount)
 	}
 }
 
+func TestPublicLabeler_CustomLabel(t *testing.T) {
+	var added any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(req.Query, "IssueByIdentifier"):
+			fmt.Fprint(w, `{"data":{"issues":{"nodes":[{"id":"issue-uuid-1","identifier":"MIR-42","labels":{"nodes":[{"id":"l-pub","name":"public"}]}}]}}}`)
+		case strings.Contains(req.Query, "LabelByName"):
+			if req.Variables["labelName"] != "published" {
+				t.Errorf("looked up label %v, want published", req.Variables["labelName"])
+			}
+			fmt.Fprint(w, `{"data":{"issueLabels":{"nodes":[{"id":"label-uuid-published","name":"published"}]}}}`)
+		case strings.Contains(req.Query, "AddLabel"):
+			added = req.Variables["labelID"]
+			fmt.Fprint(w, `{"data":{"issueAddLabel":{"success":true}}}`)
+		default:
+			t.Fatalf("unexpected query: %s", req.Query)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	client.SetPublicLabel("published")
+	labeler := NewPublicLabeler(client, "MIR")
+
+	result, err := labeler.EnsurePublicLabel(context.Background(), "MIR-42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != LabelApplied || added != "label-uuid-published" {
+		t.Errorf("result = %q adding %v; want %q adding label-uuid-published to an issue labeled only public", result, added, LabelApplied)
+	}
+}
+
+func TestPublicLabeler_RemovesLabel(t *testing.T) {
+	var removed bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		var resp any
+		switch {
+		case strings.Contains(req.Query, "IssueByIdentifier"):
+			resp = map[string]any{
+				"data": map[string]any{
+					"issues": map[string]any{
+						"nodes": []map[string]any{
+							{
+								"id":         "issue-uuid-1",
+								"identifier": "MIR-42",
+								"labels": map[string]any{
+									"nodes": []map[string]any{
+										{"id": "label-uuid-bug", "name": "bug"},
+										{"id": "label-uuid-public", "name": "public"},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+		case strings.Contains(req.Query, "RemoveLabel"):
+			removed = true
+			if req.Variables["issueID"] != "issue-uuid-1" || req.Variables["labelID"] != "label-uuid-public" {
+				t.Errorf("RemoveLabel variables = %v, want issue-uuid-1 and label-uuid-public", req.Variables)
+			}
+			resp = map[string]any{
+				"data": map[string]any{
+					"issueRemoveLabel": map[string]any{"success": true},
+				},
+			}
+		default:
+			t.Fatalf("unexpected query: %s", req.Query)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	labeler := NewPublicLabeler(client, "MIR")
+
+	if err := labeler.RemovePublicLabel(context.Background(), "MIR-42"); err != nil {
+		t.Fatalf("RemovePublicLabel: %v", err)
+	}
+	if !removed {
+		t.Error("expected the public label to be removed")
+	}
+}
+
 func TestPublicLabeler_FetchIssueError(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := map[string]any{
@@ -203,8 +340,348 @@ func TestPublicLabeler_FetchIssueError(t *testing.T) {
 	client.SetEndpoint(srv.URL)
 	labeler := NewPublicLabeler(client, "MIR")
 
-	err := labeler.EnsurePublicLabel(context.Background(), "MIR-42")
+	_, err := labeler.EnsurePublicLabel(context.Background(), "MIR-42")
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
 }
+
+func TestPublicLabeler_TeamKeys(t *testing.T) {
+	var lookedUp []any
+	added := map[any]any{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(req.Query, "IssueByIdentifier"):
+			team := req.Variables["teamKey"]
+			fmt.Fprintf(w, `{"data":{"issues":{"nodes":[{"id":"issue-%s","identifier":"%s-%v","labels":{"nodes":[]}}]}}}`, team, team, req.Variables["number"])
+		case strings.Contains(req.Query, "LabelByName"):
+			lookedUp = append(lookedUp, req.Variables["teamKey"])
+			fmt.Fprintf(w, `{"data":{"issueLabels":{"nodes":[{"id":"label-%s","name":"public"}]}}}`, req.Variables["teamKey"])
+		case strings.Contains(req.Query, "AddLabel"):
+			added[req.Variables["issueID"]] = req.Variables["labelID"]
+			fmt.Fprint(w, `{"data":{"issueAddLabel":{"success":true}}}`)
+		default:
+			t.Fatalf("unexpected query: %s", req.Query)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	labeler := NewPublicLabeler(client, "MIR")
+	labeler.SetTeamKeys("MIR", "WEB")
+
+	for _, id := range []string{"MIR-1", "WEB-2"} {
+		if _, err := labeler.EnsurePublicLabel(context.Background(), id); err != nil {
+			t.Fatalf("EnsurePublicLabel(%s): %v", id, err)
+		}
+	}
+	if want := []any{"MIR", "WEB"}; !reflect.DeepEqual(lookedUp, want) {
+		t.Errorf("label looked up in teams %v, want %v", lookedUp, want)
+	}
+	want := map[any]any{"issue-MIR": "label-MIR", "issue-WEB": "label-WEB"}
+	if !reflect.DeepEqual(added, want) {
+		t.Errorf("added labels = %v, want %v", added, want)
+	}
+}
+
+func TestLabelResolver_SharedAcrossConsumers(t *testing.T) {
+	var lookups atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !strings.Contains(req.Query, "LabelByName") {
+			t.Fatalf("unexpected query: %s", req.Query)
+		}
+		lookups.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"issueLabels":{"nodes":[{"id":"label-uuid-public","name":"public"}]}}}`)
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	resolver := NewLabelResolver(client, "MIR", "public")
+
+	labeler := NewPublicLabeler(client, "MIR")
+	labeler.SetLabelResolver(resolver)
+
+	for _, consumer := range []*LabelResolver{labeler.label, resolver} {
+		id, err := consumer.LabelID(context.Background())
+		if err != nil {
+			t.Fatalf("LabelID: %v", err)
+		}
+		if id != "label-uuid-public" {
+			t.Errorf("LabelID = %q, want %q", id, "label-uuid-public")
+		}
+	}
+	if n := lookups.Load(); n != 1 {
+		t.Errorf("LabelByName called %d times, want 1", n)
+	}
+}
+
+func TestLabelResolver_Concurrent(t *testing.T) {
+	var lookups atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"issueLabels":{"nodes":[{"id":"label-uuid-public","name":"public"}]}}}`)
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	resolver := NewLabelResolver(client, "MIR", "public")
+
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Go(func() {
+			if id, err := resolver.LabelID(context.Background()); err != nil || id != "label-uuid-public" {
+				t.Errorf("LabelID = %q, %v; want label-uuid-public", id, err)
+			}
+		})
+	}
+	wg.Wait()
+	if n := lookups.Load(); n != 1 {
+		t.Errorf("LabelByName called %d times, want 1 shared by every caller", n)
+	}
+}
+
+func TestLabelResolver_WaiterHonorsContext(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"issueLabels":{"nodes":[{"id":"label-uuid-public","name":"public"}]}}}`)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	resolver := NewLabelResolver(client, "MIR", "public")
+
+	go resolver.LabelID(context.Background())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := resolver.LabelID(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("LabelID while another lookup hangs: err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("waiter returned after %v, want it to leave when its context ended", d)
+	}
+}
+
+func TestLabelResolver_RetriesAfterError(t *testing.T) {
+	var lookups atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if lookups.Add(1) == 1 {
+			http.Error(w, "unavailable", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"issueLabels":{"nodes":[{"id":"label-uuid-public","name":"public"}]}}}`)
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	resolver := NewLabelResolver(client, "MIR", "public")
+
+	if _, err := resolver.LabelID(context.Background()); err == nil {
+		t.Fatal("expected the first lookup to fail")
+	}
+	for range 2 {
+		id, err := resolver.LabelID(context.Background())
+		if err != nil {
+			t.Fatalf("LabelID after a failed lookup: %v", err)
+		}
+		if id != "label-uuid-public" {
+			t.Errorf("LabelID = %q, want %q", id, "label-uuid-public")
+		}
+	}
+	if n := lookups.Load(); n != 2 {
+		t.Errorf("LabelByName called %d times, want 2 (the failure, then one success that is kept)", n)
+	}
+}
+
+func TestLabelResolver_CreatesMissingLabel(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    string // ID FetchLabelByName finds, if any
+		createOK    bool
+		wantID      string
+		wantCreates int
+		wantErr     bool
+	}{
+		{"already exists", "label-uuid-public", true, "label-uuid-public", 0, false},
+		{"created", "", true, "label-uuid-new", 1, false},
+		{"create fails", "", false, "", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creates := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req graphQLRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case strings.Contains(req.Query, "LabelByName"):
+					nodes := []map[string]any{}
+					if tt.existing != "" {
+						nodes = append(nodes, map[string]any{"id": tt.existing, "name": "public"})
+					}
+					json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"issueLabels": map[string]any{"nodes": nodes}}})
+				case strings.Contains(req.Query, "TeamByKey"):
+					fmt.Fprint(w, `{"data":{"teams":{"nodes":[{"id":"team-uuid-1","name":"Miren"}]}}}`)
+				case strings.Contains(req.Query, "CreateLabel"):
+					creates++
+					want := map[string]any{"teamID": "team-uuid-1", "name": "public", "color": "#5e6ad2"}
+					if !reflect.DeepEqual(req.Variables, want) {
+						t.Errorf("CreateLabel variables = %v, want %v", req.Variables, want)
+					}
+					if !tt.createOK {
+						fmt.Fprint(w, `{"data":{"issueLabelCreate":{"success":false,"issueLabel":null}}}`)
+						return
+					}
+					fmt.Fprint(w, `{"data":{"issueLabelCreate":{"success":true,"issueLabel":{"id":"label-uuid-new"}}}}`)
+				default:
+					t.Fatalf("unexpected query: %s", req.Query)
+				}
+			}))
+			defer srv.Close()
+
+			client := NewClient("test-key")
+			client.SetEndpoint(srv.URL)
+			resolver := NewLabelResolver(client, "MIR", "public")
+			resolver.SetColor("#5e6ad2")
+
+			id, err := resolver.LabelID(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LabelID error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if id != tt.wantID {
+				t.Errorf("LabelID = %q, want %q", id, tt.wantID)
+			}
+			if creates != tt.wantCreates {
+				t.Errorf("CreateLabel called %d times, want %d", creates, tt.wantCreates)
+			}
+		})
+	}
+}
+
+func TestPublicLabeler_SerializesPerIdentifier(t *testing.T) {
+	var labeled atomic.Bool
+	var adds atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		var resp any
+		switch {
+		case strings.Contains(req.Query, "IssueByIdentifier"):
+			labels := []map[string]any{}
+			if labeled.Load() {
+				labels = append(labels, map[string]any{"id": "label-uuid-public", "name": "public"})
+			}
+			resp = map[string]any{
+				"data": map[string]any{
+					"issues": map[string]any{
+						"nodes": []map[string]any{
+							{
+								"id":         "issue-uuid-1",
+								"identifier": "MIR-42",
+								"labels":     map[string]any{"nodes": labels},
+							},
+						},
+					},
+				},
+			}
+		case strings.Contains(req.Query, "LabelByName"):
+			resp = map[string]any{
+				"data": map[string]any{
+					"issueLabels": map[string]any{
+						"nodes": []map[string]any{{"id": "label-uuid-public", "name": "public"}},
+					},
+				},
+			}
+		case strings.Contains(req.Query, "AddLabel"):
+			// Give other callers time to race for the issue.
+			time.Sleep(20 * time.Millisecond)
+			adds.Add(1)
+			labeled.Store(true)
+			resp = map[string]any{
+				"data": map[string]any{
+					"issueAddLabel": map[string]any{"success": true},
+				},
+			}
+		default:
+			t.Fatalf("unexpected query: %s", req.Query)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	client := NewClient("test-key")
+	client.SetEndpoint(srv.URL)
+	labeler := NewPublicLabeler(client, "MIR")
+
+	const callers = 5
+	results := make([]LabelResult, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := labeler.EnsurePublicLabel(context.Background(), "MIR-42")
+			if err != nil {
+				t.Errorf("EnsurePublicLabel: %v", err)
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	if n := adds.Load(); n != 1 {
+		t.Errorf("AddLabel called %d times, want 1", n)
+	}
+	applied := 0
+	for _, r := range results {
+		switch r {
+		case LabelApplied:
+			applied++
+		case LabelAlreadyPublic:
+		default:
+			t.Errorf("result = %q, want %q or %q", r, LabelApplied, LabelAlreadyPublic)
+		}
+	}
+	if applied != 1 {
+		t.Errorf("%d calls applied the label, want 1", applied)
+	}
+	if len(labeler.inFlight.locks) != 0 {
+		t.Errorf("%d identifier locks left behind", len(labeler.inFlight.locks))
+	}
+
+	// With skipping enabled, a call for an identifier that's already being
+	// labeled returns without waiting.
+	labeler.SetSkipInProgress(true)
+	if err := labeler.inFlight.Lock(context.Background(), "MIR-42"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := labeler.EnsurePublicLabel(context.Background(), "MIR-42")
+	labeler.inFlight.Unlock("MIR-42")
+	if err != nil || result != LabelInProgress {
+		t.Errorf("EnsurePublicLabel while in progress = %q, %v; want %q", result, err, LabelInProgress)
+	}
+}
@@ -0,0 +1,105 @@
+package linearapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit is Linear's request budget as of the last response.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time // when Remaining refills
+}
+
+// rateLimitHeaders are the header names tried, in order, for each field.
+// Linear sends the X-RateLimit-Requests-* ones; the generic names cover
+// proxies and API versions that use them instead.
+var rateLimitHeaders = struct {
+	limit, remaining, reset []string
+}{
+	limit:     []string{"X-RateLimit-Requests-Limit", "X-RateLimit-Limit"},
+	remaining: []string{"X-RateLimit-Requests-Remaining", "X-RateLimit-Remaining"},
+	reset:     []string{"X-RateLimit-Requests-Reset", "X-RateLimit-Reset"},
+}
+
+// rateLimiter remembers the last rate limit Linear reported, so requests can
+// wait for the budget to refill instead of failing with 429.
+type rateLimiter struct {
+	mu    sync.Mutex
+	state RateLimit
+	seen  bool
+}
+
+// RateLimit returns the rate limit from Linear's last response, and false if
+// no response has reported one yet.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.limiter.mu.Lock()
+	defer c.limiter.mu.Unlock()
+	return c.limiter.state, c.limiter.seen
+}
+
+// observe records the rate limit headers of a response, if it has them.
+func (l *rateLimiter) observe(h http.Header) {
+	remaining, ok := headerInt(h, rateLimitHeaders.remaining)
+	if !ok {
+		return
+	}
+	state := RateLimit{Remaining: remaining}
+	state.Limit, _ = headerInt(h, rateLimitHeaders.limit)
+	if reset, ok := headerInt(h, rateLimitHeaders.reset); ok {
+		state.Reset = resetTime(int64(reset))
+	}
+
+	l.mu.Lock()
+	l.state, l.seen = state, true
+	l.mu.Unlock()
+}
+
+// wait blocks until the budget has refilled if the last response said it was
+// used up, returning early with ctx's error if ctx is done first.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	state, seen := l.state, l.seen
+	l.mu.Unlock()
+	if !seen || state.Remaining > 0 {
+		return nil
+	}
+	d := time.Until(state.Reset)
+	if d <= 0 {
+		return nil
+	}
+
+	slog.Warn("Linear rate limit reached, waiting for reset", "reset", state.Reset, "wait", d)
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func headerInt(h http.Header, names []string) (int, bool) {
+	for _, name := range names {
+		if v := h.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// resetTime converts a reset header, which Linear sends as Unix milliseconds
+// and other APIs as Unix seconds, to a time.
+func resetTime(v int64) time.Time {
+	if v > 1e12 {
+		return time.UnixMilli(v)
+	}
+	return time.Unix(v, 0)
+}
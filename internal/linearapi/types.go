@@ -1,7 +1,12 @@
 package linearapi
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/url"
 	"regexp"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -14,20 +19,151 @@ type Issue struct {
 	Priority    int
 	Labels      []Label
 	Attachments []Attachment
+	Reactions   []Reaction
+	History     []HistoryEvent
+	Comments    []Comment // oldest first; only fetched when enabled
+	Project     *Project  // nil when the issue is not in a project
+	DuplicateOf string    // identifier of the issue this one duplicates, if any
 	URL         string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+
+	// foldLabels makes label lookups ignore case; see
+	// Client.SetCaseInsensitiveLabels.
+	foldLabels bool
+	// publicLabel is the label that publishes the issue, or "" for
+	// DefaultPublicLabel; see Client.SetPublicLabel.
+	publicLabel string
 }
 
 type Attachment struct {
 	URL   string
 	Title string
+	// Status is the pull request state Linear's GitHub integration reports,
+	// such as "open", "draft", "merged", or "closed". It is empty for other
+	// attachments and for PRs linked by hand.
+	Status string
+}
+
+// PRSummary counts linked pull requests for an at-a-glance "is this
+// shipped?" line.
+type PRSummary struct {
+	Total  int
+	Merged int
+}
+
+// SummarizePRs counts prs and how many of them are merged. ok is false when
+// no PR has a known status, since a merged count would then be meaningless.
+func SummarizePRs(prs []Attachment) (s PRSummary, ok bool) {
+	for _, pr := range prs {
+		s.Total++
+		if pr.Status != "" {
+			ok = true
+		}
+		if pr.Status == "merged" {
+			s.Merged++
+		}
+	}
+	return s, ok
+}
+
+func (s PRSummary) String() string {
+	noun := "PRs"
+	if s.Total == 1 {
+		noun = "PR"
+	}
+	return fmt.Sprintf("%d %s, %d merged", s.Total, noun, s.Merged)
+}
+
+// Reaction is the number of times an emoji was used to react to an issue.
+type Reaction struct {
+	Emoji string
+	Count int
+}
+
+// HistoryEvent is a state change or label addition on an issue, oldest
+// first. Who made the change is deliberately not kept, since actors and
+// other history fields may not be public; for the same reason only labels
+// the issue still has, and that aren't private, are listed.
+type HistoryEvent struct {
+	CreatedAt   time.Time
+	FromState   string
+	ToState     string
+	AddedLabels []string
+}
+
+// publicHistory returns the issue's history without the label additions that
+// could leak: labels the issue no longer has, such as internal triage labels,
+// and private labels. Events left with nothing to show are dropped.
+func (i *Issue) publicHistory() []HistoryEvent {
+	var history []HistoryEvent
+	for _, ev := range i.History {
+		var added []string
+		for _, name := range ev.AddedLabels {
+			private := slices.ContainsFunc(privateLabels, func(p string) bool { return strings.EqualFold(p, name) })
+			if i.HasLabel(name) && !private {
+				added = append(added, name)
+			}
+		}
+		ev.AddedLabels = added
+		if ev.ToState != "" || len(added) > 0 {
+			history = append(history, ev)
+		}
+	}
+	return history
+}
+
+// Comment is a comment on an issue.
+type Comment struct {
+	Author    string // display name of the user or integration that wrote it
+	Body      string // markdown
+	CreatedAt time.Time
+}
+
+type Project struct {
+	Name  string
+	URL   string
+	State string // planned, started, paused, completed, canceled
 }
 
 type State struct {
 	Name  string
 	Color string
-	Type  string // backlog, unstarted, started, completed, cancelled
+	Type  string // triage, backlog, unstarted, started, completed, canceled
+}
+
+// Canceled reports whether the issue was closed without being done, which
+// includes being marked a duplicate.
+func (i *Issue) Canceled() bool {
+	return i.State.Type == "canceled" || i.DuplicateOf != ""
+}
+
+// encodedIssue is Issue with foldLabels and publicLabel exported, so issues
+// keep how their labels are matched across a round trip through a shared
+// cache.
+type encodedIssue struct {
+	plainIssue
+	FoldLabels  bool   `json:",omitempty"`
+	PublicLabel string `json:",omitempty"`
+}
+
+// plainIssue has Issue's fields but not its methods, so encoding it doesn't
+// recurse into MarshalJSON.
+type plainIssue Issue
+
+func (i Issue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encodedIssue{plainIssue(i), i.foldLabels, i.publicLabel})
+}
+
+func (i *Issue) UnmarshalJSON(data []byte) error {
+	var v encodedIssue
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*i = Issue(v.plainIssue)
+	i.foldLabels = v.FoldLabels
+	i.publicLabel = v.PublicLabel
+	return nil
 }
 
 type Label struct {
@@ -37,22 +173,96 @@ type Label struct {
 }
 
 func (i *Issue) HasLabel(name string) bool {
+	_, ok := i.Label(name)
+	return ok
+}
+
+// PublicLabel returns the name of the label that publishes the issue.
+func (i *Issue) PublicLabel() string {
+	if i.publicLabel == "" {
+		return DefaultPublicLabel
+	}
+	return i.publicLabel
+}
+
+// Label returns the issue's label called name.
+func (i *Issue) Label(name string) (Label, bool) {
 	for _, l := range i.Labels {
-		if l.Name == name {
-			return true
+		if l.Name == name || i.foldLabels && strings.EqualFold(l.Name, name) {
+			return l, true
 		}
 	}
-	return false
+	return Label{}, false
 }
 
-var githubPRPattern = regexp.MustCompile(`^https://github\.com/.+/pull/\d+`)
+var githubPRPathPattern = regexp.MustCompile(`^/[^/]+/[^/]+/pull/\d+`)
 
-func (i *Issue) GitHubPRs() []Attachment {
+// GitHubPRs returns attachments that link to pull requests on github.com or
+// on any of the given GitHub Enterprise hosts.
+func (i *Issue) GitHubPRs(extraHosts ...string) []Attachment {
 	var prs []Attachment
 	for _, a := range i.Attachments {
-		if githubPRPattern.MatchString(a.URL) {
+		if isGitHubPR(a.URL, extraHosts) {
 			prs = append(prs, a)
 		}
 	}
 	return prs
 }
+
+// AttachmentURL returns the URL of the first http(s) attachment titled
+// title, ignoring case.
+func (i *Issue) AttachmentURL(title string) (string, bool) {
+	for _, a := range i.Attachments {
+		if !strings.EqualFold(a.Title, title) {
+			continue
+		}
+		if u, err := url.Parse(a.URL); err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" {
+			return a.URL, true
+		}
+	}
+	return "", false
+}
+
+func isGitHubPR(rawURL string, extraHosts []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	if u.Host != "github.com" && !slices.Contains(extraHosts, u.Host) {
+		return false
+	}
+	return githubPRPathPattern.MatchString(u.Path)
+}
+
+// GateMode decides which issues are shown publicly.
+type GateMode string
+
+const (
+	// GateAllowlist shows only issues with the public label, "public"
+	// unless Client.SetPublicLabel changes it, and not also labeled
+	// "private" or "confidential". It is the default.
+	GateAllowlist GateMode = "allowlist"
+	// GateDenylist shows every issue not labeled "private" or "confidential".
+	GateDenylist GateMode = "denylist"
+)
+
+// ParseGateMode validates a GATE_MODE value; empty means GateAllowlist.
+func ParseGateMode(s string) (GateMode, error) {
+	switch m := GateMode(s); m {
+	case "":
+		return GateAllowlist, nil
+	case GateAllowlist, GateDenylist:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown gate mode %q (want allowlist or denylist)", s)
+	}
+}
+
+// IsPublic reports whether the issue may be shown under mode m. A private
+// label wins over the public one in either mode.
+func (m GateMode) IsPublic(i *Issue) bool {
+	if slices.ContainsFunc(privateLabels, i.HasLabel) {
+		return false
+	}
+	return m == GateDenylist || i.HasLabel(i.PublicLabel())
+}
@@ -0,0 +1,160 @@
+package linearapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGitHubPRs(t *testing.T) {
+	issue := &Issue{
+		Attachments: []Attachment{
+			{URL: "https://github.com/org/repo/pull/1", Title: "public PR"},
+			{URL: "https://ghe.example.com/org/repo/pull/2", Title: "enterprise PR"},
+			{URL: "https://github.com/org/repo/issues/3", Title: "issue"},
+			{URL: "https://linear.app/miren/issue/MIR-1", Title: "linear"},
+		},
+	}
+
+	prs := issue.GitHubPRs()
+	if len(prs) != 1 || prs[0].Title != "public PR" {
+		t.Errorf("GitHubPRs() = %v, want only the github.com PR", prs)
+	}
+
+	prs = issue.GitHubPRs("ghe.example.com")
+	if len(prs) != 2 {
+		t.Fatalf("GitHubPRs(ghe.example.com) count = %d, want 2", len(prs))
+	}
+	if prs[1].Title != "enterprise PR" {
+		t.Errorf("prs[1].Title = %q, want %q", prs[1].Title, "enterprise PR")
+	}
+}
+
+func TestHasLabel(t *testing.T) {
+	issue := &Issue{Labels: []Label{{ID: "l1", Name: "Public"}}}
+	if issue.HasLabel("public") {
+		t.Error("HasLabel should be case-sensitive by default")
+	}
+
+	issue.foldLabels = true
+	label, ok := issue.Label("public")
+	if !ok || label.ID != "l1" {
+		t.Errorf("Label(public) = %+v, %t; want the Public label", label, ok)
+	}
+	if !GateAllowlist.IsPublic(issue) {
+		t.Error("issue labeled Public should be public with case-insensitive labels")
+	}
+}
+
+func TestIssueJSONKeepsFoldLabels(t *testing.T) {
+	issue := &Issue{Identifier: "MIR-1", Labels: []Label{{ID: "l1", Name: "Public"}}, foldLabels: true}
+	data, err := json.Marshal(issue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Issue
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Identifier != "MIR-1" || !got.HasLabel("public") {
+		t.Errorf("round trip = %+v; want MIR-1 with case-insensitive labels", got)
+	}
+}
+
+func TestIssueJSONKeepsPublicLabel(t *testing.T) {
+	issue := &Issue{Identifier: "MIR-1", Labels: []Label{{ID: "l1", Name: "published"}}, publicLabel: "published"}
+	data, err := json.Marshal(issue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Issue
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.PublicLabel() != "published" || !GateAllowlist.IsPublic(&got) {
+		t.Errorf("round trip = %+v; want an issue published by its published label", got)
+	}
+}
+
+func TestParseGateMode(t *testing.T) {
+	for in, want := range map[string]GateMode{"": GateAllowlist, "allowlist": GateAllowlist, "denylist": GateDenylist} {
+		got, err := ParseGateMode(in)
+		if err != nil || got != want {
+			t.Errorf("ParseGateMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseGateMode("everything"); err == nil {
+		t.Error("expected error for unknown gate mode")
+	}
+}
+
+func TestPublicHistory(t *testing.T) {
+	at := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	issue := &Issue{
+		Labels: []Label{{Name: "public"}, {Name: "bug"}, {Name: "Confidential"}},
+		History: []HistoryEvent{
+			{CreatedAt: at, AddedLabels: []string{"bug", "needs-triage"}},
+			{CreatedAt: at, AddedLabels: []string{"confidential"}},
+			{CreatedAt: at, ToState: "Done", AddedLabels: []string{"Confidential"}},
+			{CreatedAt: at, AddedLabels: []string{"removed-later"}},
+		},
+	}
+	want := []HistoryEvent{
+		{CreatedAt: at, AddedLabels: []string{"bug"}},
+		{CreatedAt: at, ToState: "Done"},
+	}
+	if got := issue.publicHistory(); !reflect.DeepEqual(got, want) {
+		t.Errorf("publicHistory = %+v, want %+v", got, want)
+	}
+}
+
+func TestGateModeIsPublic(t *testing.T) {
+	tests := []struct {
+		labels        []string
+		allow, denied bool
+	}{
+		{nil, false, true},
+		{[]string{"public"}, true, true},
+		{[]string{"private"}, false, false},
+		{[]string{"public", "private"}, false, false},
+		{[]string{"confidential", "public"}, false, false},
+	}
+	for _, tt := range tests {
+		issue := &Issue{}
+		for _, name := range tt.labels {
+			issue.Labels = append(issue.Labels, Label{Name: name})
+		}
+		if got := GateAllowlist.IsPublic(issue); got != tt.allow {
+			t.Errorf("allowlist: labels %v IsPublic = %t, want %t", tt.labels, got, tt.allow)
+		}
+		if got := GateDenylist.IsPublic(issue); got != tt.denied {
+			t.Errorf("denylist: labels %v IsPublic = %t, want %t", tt.labels, got, tt.denied)
+		}
+	}
+}
+
+func TestSummarizePRs(t *testing.T) {
+	tests := []struct {
+		name   string
+		prs    []Attachment
+		want   string
+		wantOK bool
+	}{
+		{"none", nil, "", false},
+		{"no status", []Attachment{{}, {}}, "", false},
+		{"one merged", []Attachment{{Status: "merged"}}, "1 PR, 1 merged", true},
+		{"mixed", []Attachment{{Status: "merged"}, {Status: "open"}, {}}, "3 PRs, 1 merged", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := SummarizePRs(tt.prs)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && s.String() != tt.want {
+				t.Errorf("summary = %q, want %q", s, tt.want)
+			}
+		})
+	}
+}
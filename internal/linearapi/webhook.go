@@ -0,0 +1,164 @@
+package linearapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const maxWebhookBody = 1 << 20 // 1 MB
+
+// webhookTimeout bounds the Linear calls made for one delivery.
+const webhookTimeout = 30 * time.Second
+
+// privateLabels mark an issue as not for the public site, whatever other
+// labels it has.
+var privateLabels = []string{"private", "confidential"}
+
+// Cache forgets what is cached about an issue.
+type Cache interface {
+	Delete(identifier string)
+}
+
+// Unpublisher takes issues off the public site.
+type Unpublisher interface {
+	RemovePublicLabel(ctx context.Context, identifier string) error
+}
+
+// WebhookHandler receives Linear's issue webhooks. Every issue change drops
+// the issue from the cache so edits show up at once, and when an unpublisher
+// is set, an issue labeled both public and "private" or "confidential" loses
+// its public label so the conflict resolves toward private.
+type WebhookHandler struct {
+	secret      []byte
+	teamKeys    []string
+	cache       Cache
+	unpublisher Unpublisher
+	publicLabel string
+}
+
+func NewWebhookHandler(secret, teamKey string, cache Cache) *WebhookHandler {
+	return &WebhookHandler{
+		secret:      []byte(secret),
+		teamKeys:    []string{strings.ToUpper(teamKey)},
+		cache:       cache,
+		publicLabel: DefaultPublicLabel,
+	}
+}
+
+// SetTeamKeys makes the handler act on issues of every team in keys instead
+// of only the one it was created for.
+func (h *WebhookHandler) SetTeamKeys(keys ...string) {
+	h.teamKeys = make([]string, len(keys))
+	for i, key := range keys {
+		h.teamKeys[i] = strings.ToUpper(key)
+	}
+}
+
+// servesTeam reports whether id belongs to one of the handler's teams.
+func (h *WebhookHandler) servesTeam(id string) bool {
+	teamKey, _, ok := strings.Cut(id, "-")
+	return ok && slices.Contains(h.teamKeys, teamKey)
+}
+
+// SetPublicLabel changes the name of the label that marks issues public; see
+// Client.SetPublicLabel.
+func (h *WebhookHandler) SetPublicLabel(name string) {
+	h.publicLabel = name
+}
+
+// SetUnpublisher enables removing the public label from issues that also
+// have a private or confidential label.
+func (h *WebhookHandler) SetUnpublisher(u Unpublisher) {
+	h.unpublisher = u
+}
+
+// issueEvent is the part of a Linear webhook payload the handler reads.
+// UpdatedFrom holds the previous values of changed fields, so LabelIDs is
+// nil when the labels didn't change.
+type issueEvent struct {
+	Action string `json:"action"`
+	Type   string `json:"type"`
+	Data   struct {
+		Identifier string       `json:"identifier"`
+		Labels     []eventLabel `json:"labels"`
+	} `json:"data"`
+	UpdatedFrom struct {
+		LabelIDs []string `json:"labelIds"`
+	} `json:"updatedFrom"`
+}
+
+type eventLabel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// hasPrivateLabel reports whether the event leaves the issue with a private
+// label.
+func (e *issueEvent) hasPrivateLabel() bool {
+	return slices.ContainsFunc(privateLabels, e.hasLabel)
+}
+
+func (e *issueEvent) hasLabel(name string) bool {
+	return slices.ContainsFunc(e.Data.Labels, func(l eventLabel) bool {
+		return strings.EqualFold(l.Name, name)
+	})
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if !h.verifySignature(body, r.Header.Get("Linear-Signature")) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	var event issueEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	id := strings.ToUpper(event.Data.Identifier)
+	if event.Type != "Issue" || !h.servesTeam(id) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Whichever label came first, an issue left with both is unpublished.
+	if h.unpublisher != nil && event.hasLabel(h.publicLabel) && event.hasPrivateLabel() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), webhookTimeout)
+		defer cancel()
+		if err := h.unpublisher.RemovePublicLabel(ctx, id); err != nil {
+			slog.Error("failed to remove public label from private issue", "identifier", id, "error", err)
+		} else {
+			slog.Info("unpublished issue labeled private", "identifier", id)
+		}
+	}
+	// Delete after any unpublishing so the next read sees its result.
+	h.cache.Delete(id)
+	w.WriteHeader(http.StatusOK)
+}
+
+// verifySignature checks the Linear-Signature header, a hex HMAC-SHA256 of
+// the body keyed by the webhook's signing secret.
+func (h *WebhookHandler) verifySignature(body []byte, signature string) bool {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
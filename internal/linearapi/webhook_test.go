@@ -0,0 +1,181 @@
+package linearapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type mockCache struct {
+	invalidated []string
+}
+
+func (m *mockCache) Delete(identifier string) {
+	m.invalidated = append(m.invalidated, identifier)
+}
+
+type mockUnpublisher struct {
+	removed []string
+}
+
+func (m *mockUnpublisher) RemovePublicLabel(_ context.Context, identifier string) error {
+	m.removed = append(m.removed, identifier)
+	return nil
+}
+
+func signLinear(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		publicLabel     string // passed to SetPublicLabel if set
+		body            string
+		wantRemoved     []string
+		wantInvalidated []string
+	}{
+		{
+			name:            "confidential added to public issue",
+			body:            `{"action":"update","type":"Issue","data":{"identifier":"MIR-42","labels":[{"id":"l-pub","name":"public"},{"id":"l-conf","name":"Confidential"}]},"updatedFrom":{"labelIds":["l-pub"]}}`,
+			wantRemoved:     []string{"MIR-42"},
+			wantInvalidated: []string{"MIR-42"},
+		},
+		{
+			name:            "created public and private",
+			body:            `{"action":"create","type":"Issue","data":{"identifier":"MIR-42","labels":[{"id":"l-pub","name":"public"},{"id":"l-priv","name":"private"}]}}`,
+			wantRemoved:     []string{"MIR-42"},
+			wantInvalidated: []string{"MIR-42"},
+		},
+		{
+			name:            "public added to private issue",
+			body:            `{"action":"update","type":"Issue","data":{"identifier":"MIR-42","labels":[{"id":"l-pub","name":"public"},{"id":"l-priv","name":"private"}]},"updatedFrom":{"labelIds":["l-priv"]}}`,
+			wantRemoved:     []string{"MIR-42"},
+			wantInvalidated: []string{"MIR-42"},
+		},
+		{
+			name:            "labels unchanged but conflicting",
+			body:            `{"action":"update","type":"Issue","data":{"identifier":"MIR-42","labels":[{"id":"l-pub","name":"public"},{"id":"l-priv","name":"private"}]},"updatedFrom":{"title":"Old"}}`,
+			wantRemoved:     []string{"MIR-42"},
+			wantInvalidated: []string{"MIR-42"},
+		},
+		{
+			name:            "public removed from private issue",
+			body:            `{"action":"update","type":"Issue","data":{"identifier":"MIR-42","labels":[{"id":"l-conf","name":"confidential"}]},"updatedFrom":{"labelIds":["l-pub","l-conf"]}}`,
+			wantInvalidated: []string{"MIR-42"},
+		},
+		{
+			name:            "private added to unpublished issue",
+			body:            `{"action":"update","type":"Issue","data":{"identifier":"MIR-42","labels":[{"id":"l-priv","name":"private"}]},"updatedFrom":{"labelIds":[]}}`,
+			wantInvalidated: []string{"MIR-42"},
+		},
+		{
+			name:            "custom public label",
+			publicLabel:     "published",
+			body:            `{"action":"update","type":"Issue","data":{"identifier":"MIR-42","labels":[{"id":"l-pub","name":"published"},{"id":"l-priv","name":"private"}]},"updatedFrom":{"labelIds":["l-pub"]}}`,
+			wantRemoved:     []string{"MIR-42"},
+			wantInvalidated: []string{"MIR-42"},
+		},
+		{
+			name:            "public is not the custom label",
+			publicLabel:     "published",
+			body:            `{"action":"update","type":"Issue","data":{"identifier":"MIR-42","labels":[{"id":"l-pub","name":"public"},{"id":"l-priv","name":"private"}]},"updatedFrom":{"labelIds":["l-pub"]}}`,
+			wantInvalidated: []string{"MIR-42"},
+		},
+		{
+			name: "other team",
+			body: `{"action":"update","type":"Issue","data":{"identifier":"WEB-1","labels":[{"id":"l-pub","name":"public"},{"id":"l-priv","name":"private"}]},"updatedFrom":{"labelIds":[]}}`,
+		},
+		{
+			name: "not an issue",
+			body: `{"action":"create","type":"Comment","data":{"id":"c-1"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &mockCache{}
+			unpublisher := &mockUnpublisher{}
+			h := NewWebhookHandler("secret", "mir", cache)
+			h.SetUnpublisher(unpublisher)
+			if tt.publicLabel != "" {
+				h.SetPublicLabel(tt.publicLabel)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook/linear", strings.NewReader(tt.body))
+			req.Header.Set("Linear-Signature", signLinear("secret", tt.body))
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+			}
+			if !slices.Equal(unpublisher.removed, tt.wantRemoved) {
+				t.Errorf("removed = %v, want %v", unpublisher.removed, tt.wantRemoved)
+			}
+			if !slices.Equal(cache.invalidated, tt.wantInvalidated) {
+				t.Errorf("invalidated = %v, want %v", cache.invalidated, tt.wantInvalidated)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_TeamKeys(t *testing.T) {
+	cache := &mockCache{}
+	h := NewWebhookHandler("secret", "MIR", cache)
+	h.SetTeamKeys("MIR", "web")
+
+	for _, id := range []string{"MIR-1", "WEB-2", "API-3"} {
+		body := `{"action":"update","type":"Issue","data":{"identifier":"` + id + `"}}`
+		req := httptest.NewRequest(http.MethodPost, "/webhook/linear", strings.NewReader(body))
+		req.Header.Set("Linear-Signature", signLinear("secret", body))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if want := []string{"MIR-1", "WEB-2"}; !slices.Equal(cache.invalidated, want) {
+		t.Errorf("invalidated = %v, want %v", cache.invalidated, want)
+	}
+}
+
+func TestWebhookHandler_NoUnpublisher(t *testing.T) {
+	cache := &mockCache{}
+	h := NewWebhookHandler("secret", "MIR", cache)
+
+	body := `{"action":"update","type":"Issue","data":{"identifier":"MIR-42","labels":[{"id":"l-pub","name":"public"},{"id":"l-conf","name":"confidential"}]},"updatedFrom":{"labelIds":["l-pub"]}}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/linear", strings.NewReader(body))
+	req.Header.Set("Linear-Signature", signLinear("secret", body))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if !slices.Equal(cache.invalidated, []string{"MIR-42"}) {
+		t.Errorf("invalidated = %v, want [MIR-42]", cache.invalidated)
+	}
+}
+
+func TestWebhookHandler_InvalidSignature(t *testing.T) {
+	cache := &mockCache{}
+	h := NewWebhookHandler("secret", "MIR", cache)
+
+	body := `{"action":"update","type":"Issue","data":{"identifier":"MIR-42"}}`
+	for _, sig := range []string{"", "zz", signLinear("wrong", body)} {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/linear", strings.NewReader(body))
+		req.Header.Set("Linear-Signature", sig)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("signature %q: status = %d, want %d", sig, rr.Code, http.StatusForbidden)
+		}
+	}
+	if len(cache.invalidated) != 0 {
+		t.Errorf("invalidated %v on unsigned deliveries", cache.invalidated)
+	}
+}
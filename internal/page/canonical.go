@@ -0,0 +1,28 @@
+package page
+
+import "miren.dev/linear-issue-bridge/internal/linearapi"
+
+// SetCanonicalAttachment makes issues with an attachment of the given title,
+// e.g. "Public URL", use its link as their canonical URL, so a blog post or
+// changelog entry about the issue ranks instead of the bridge page.
+func (r *Renderer) SetCanonicalAttachment(title string) {
+	r.canonicalAttachment = title
+}
+
+// CanonicalOverride returns the canonical URL issue declares through its
+// canonical attachment, if any.
+func (r *Renderer) CanonicalOverride(issue *linearapi.Issue) (string, bool) {
+	if r.canonicalAttachment == "" {
+		return "", false
+	}
+	return issue.AttachmentURL(r.canonicalAttachment)
+}
+
+// canonicalURL is issue's canonical attachment link, or else its page on the
+// bridge.
+func (r *Renderer) canonicalURL(issue *linearapi.Issue) string {
+	if u, ok := r.CanonicalOverride(issue); ok {
+		return u
+	}
+	return "/" + issue.Identifier
+}
@@ -0,0 +1,63 @@
+package page
+
+import (
+	"io"
+	"strings"
+
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+)
+
+const (
+	cardTitleTop    = 240
+	cardLineHeight  = 80
+	cardLineChars   = 30
+	cardMaxLines    = 3
+	cardDefaultFill = "#94a3b8"
+)
+
+type cardData struct {
+	Identifier string
+	TitleLines []string
+	StateName  string
+	StateColor string
+}
+
+// RenderCardSVG writes a 1200x630 social card for the issue, suitable for
+// og:image.
+func (r *Renderer) RenderCardSVG(w io.Writer, issue *linearapi.Issue) error {
+	color := issue.State.Color
+	if color == "" {
+		color = cardDefaultFill
+	}
+	return r.templates.ExecuteTemplate(w, "card.svg", cardData{
+		Identifier: issue.Identifier,
+		TitleLines: wrapTitle(issue.Title, cardLineChars, cardMaxLines),
+		StateName:  issue.State.Name,
+		StateColor: color,
+	})
+}
+
+// wrapTitle breaks title into at most maxLines lines of roughly width
+// characters, since SVG text doesn't wrap. Overflow is cut with an ellipsis.
+func wrapTitle(title string, width, maxLines int) []string {
+	var lines []string
+	var line strings.Builder
+	for _, word := range strings.Fields(title) {
+		if line.Len() > 0 && line.Len()+1+len(word) > width {
+			lines = append(lines, line.String())
+			line.Reset()
+		}
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(word)
+	}
+	if line.Len() > 0 {
+		lines = append(lines, line.String())
+	}
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		lines[maxLines-1] += "…"
+	}
+	return lines
+}
@@ -0,0 +1,39 @@
+package page
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// textColor picks black or white text, whichever contrasts more with the
+// background color hex ("#rrggbb" or "#rgb"). Unparseable colors get
+// "inherit" so the chip falls back to the page's text color.
+func textColor(hex string) string {
+	h := strings.TrimPrefix(hex, "#")
+	if len(h) == 3 {
+		h = string([]byte{h[0], h[0], h[1], h[1], h[2], h[2]})
+	}
+	if len(h) != 6 {
+		return "inherit"
+	}
+	v, err := strconv.ParseUint(h, 16, 32)
+	if err != nil {
+		return "inherit"
+	}
+
+	// WCAG relative luminance. Above 0.179, black text has the higher
+	// contrast ratio.
+	channel := func(c uint64) float64 {
+		s := float64(c) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	l := 0.2126*channel(v>>16&0xff) + 0.7152*channel(v>>8&0xff) + 0.0722*channel(v&0xff)
+	if l > 0.179 {
+		return "#000000"
+	}
+	return "#ffffff"
+}
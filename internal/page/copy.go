@@ -0,0 +1,11 @@
+package page
+
+const copyContainer = `<div class="code-block">`
+
+const copyButton = `<button type="button" class="copy-button" aria-label="Copy code">Copy</button>`
+
+// SetCopyButtons wraps fenced code blocks in a container with a button that
+// copies the block's text to the clipboard.
+func (r *Renderer) SetCopyButtons(enabled bool) {
+	r.copyButtons = enabled
+}
@@ -0,0 +1,111 @@
+package page
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+)
+
+// jsonFeed is a JSON Feed 1.1 document (https://jsonfeed.org/version/1.1).
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string    `json:"id"`
+	URL           string    `json:"url"`
+	Title         string    `json:"title"`
+	ContentHTML   string    `json:"content_html,omitempty"`
+	ContentText   string    `json:"content_text,omitempty"`
+	Summary       string    `json:"summary,omitempty"`
+	DatePublished time.Time `json:"date_published"`
+	DateModified  time.Time `json:"date_modified"`
+	Tags          []string  `json:"tags,omitempty"`
+}
+
+// FeedContent is how much of each issue's description feed items carry.
+type FeedContent string
+
+const (
+	// FeedFull includes the rendered description. It is the default.
+	FeedFull FeedContent = "full"
+	// FeedExcerpt includes a short plain-text excerpt of the description.
+	FeedExcerpt FeedContent = "excerpt"
+	// FeedNone leaves the description out.
+	FeedNone FeedContent = "none"
+)
+
+// ParseFeedContent validates a FEED_CONTENT value; empty means FeedFull.
+func ParseFeedContent(s string) (FeedContent, error) {
+	switch c := FeedContent(s); c {
+	case "":
+		return FeedFull, nil
+	case FeedFull, FeedExcerpt, FeedNone:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown feed content %q (want full, excerpt, or none)", s)
+	}
+}
+
+// RenderJSONFeed writes issues as a JSON Feed. baseURL is the scheme and host
+// the pages are served from, since feed URLs must be absolute. Items link to
+// the public pages rather than Linear. content chooses how much of each
+// description items carry, which keeps the feed small when descriptions are
+// long.
+func (r *Renderer) RenderJSONFeed(w io.Writer, baseURL string, issues []*linearapi.Issue, content FeedContent) error {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       "Miren public issues",
+		HomePageURL: baseURL + "/",
+		FeedURL:     baseURL + "/feed.json",
+		Items:       make([]jsonFeedItem, len(issues)),
+	}
+	for i, issue := range issues {
+		var tags []string
+		for _, l := range issue.Labels {
+			tags = append(tags, l.Name)
+		}
+		item := jsonFeedItem{
+			ID:            issue.Identifier,
+			URL:           baseURL + "/" + issue.Identifier,
+			Title:         issue.Identifier + ": " + issue.Title,
+			DatePublished: issue.CreatedAt,
+			DateModified:  issue.UpdatedAt,
+			Tags:          tags,
+		}
+		// JSON Feed requires content_html or content_text, so the
+		// excerpt, or failing that the title, doubles as the content.
+		switch content {
+		case FeedExcerpt:
+			item.Summary = r.excerpt(issue.Description)
+			item.ContentText = item.Summary
+		case FeedNone:
+			item.ContentText = item.Title
+		default:
+			item.ContentHTML = string(r.renderMarkdown(issue.Description))
+		}
+		feed.Items[i] = item
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(feed)
+}
+
+// excerptLength is how many characters of a description a summary feed item
+// carries.
+const excerptLength = 280
+
+// excerpt is the start of a description's Markdown source with runs of
+// whitespace collapsed, so it reads as one paragraph.
+func (r *Renderer) excerpt(description string) string {
+	return truncate(strings.Join(strings.Fields(r.redact(description)), " "), excerptLength)
+}
@@ -0,0 +1,61 @@
+package page
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// icons are served from the site root, where browsers look for them, with
+// their content types spelled out since .ico has no stdlib MIME mapping.
+var icons = map[string]string{
+	"favicon.ico":          "image/x-icon",
+	"favicon.svg":          "image/svg+xml",
+	"apple-touch-icon.png": "image/png",
+}
+
+const iconCacheControl = "public, max-age=604800"
+
+// SetAssetDir makes icons found in dir replace the built-in ones.
+func (r *Renderer) SetAssetDir(dir string) {
+	r.assetDir = dir
+}
+
+// IconHandler serves the favicon and touch icons.
+func (r *Renderer) IconHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name := path.Base(req.URL.Path)
+		contentType, ok := icons[name]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+
+		data, err := r.readIcon(name)
+		if err != nil {
+			slog.Error("read icon", "name", name, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", iconCacheControl)
+		http.ServeContent(w, req, name, time.Time{}, bytes.NewReader(data))
+	})
+}
+
+func (r *Renderer) readIcon(name string) ([]byte, error) {
+	if r.assetDir != "" {
+		data, err := os.ReadFile(filepath.Join(r.assetDir, name))
+		if !errors.Is(err, fs.ErrNotExist) {
+			return data, err
+		}
+	}
+	return staticFS.ReadFile("static/" + name)
+}
@@ -0,0 +1,129 @@
+package page
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+)
+
+// previewTimeout bounds the lookup of issues referenced from a description.
+// Previews are cosmetic, so a slow lookup shouldn't hold up the page.
+const previewTimeout = 2 * time.Second
+
+// IssueLookup resolves several issues at once, omitting ones that don't
+// exist.
+type IssueLookup interface {
+	GetMany(ctx context.Context, identifiers []string) (map[string]*linearapi.Issue, error)
+}
+
+var linearIssueURLPattern = regexp.MustCompile(`(?i)^https://linear\.app/[^/]+/issue/([a-z][a-z0-9]*-\d+)(?:[/?#].*)?$`)
+
+// linkTransformer points links to Linear issues of our team at the bridge's
+// own page for that issue, since readers can't follow links into Linear.
+type linkTransformer struct {
+	r *Renderer
+}
+
+func (t *linkTransformer) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	source := reader.Source()
+
+	var autoLinks []*ast.AutoLink
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			if path, ok := t.r.bridgePath(string(n.Destination)); ok {
+				n.Destination = []byte(path)
+			}
+		case *ast.AutoLink:
+			if n.AutoLinkType == ast.AutoLinkURL {
+				autoLinks = append(autoLinks, n)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// Autolinks carry their URL as source text, so swap them for regular
+	// links to change where they point.
+	for _, al := range autoLinks {
+		path, ok := t.r.bridgePath(string(al.URL(source)))
+		if !ok {
+			continue
+		}
+		link := ast.NewLink()
+		link.Destination = []byte(path)
+		link.AppendChild(link, ast.NewString(al.Label(source)))
+		al.Parent().ReplaceChild(al.Parent(), al, link)
+	}
+}
+
+// bridgePath returns the bridge path for a Linear issue URL belonging to the
+// renderer's team.
+func (r *Renderer) bridgePath(url string) (string, bool) {
+	m := linearIssueURLPattern.FindStringSubmatch(url)
+	if m == nil {
+		return "", false
+	}
+	identifier := strings.ToUpper(m[1])
+	if !strings.HasPrefix(identifier, strings.ToUpper(r.teamKey)+"-") {
+		return "", false
+	}
+	return "/" + identifier, true
+}
+
+// annotateIssueLinks sets the title of links to other public issues to that
+// issue's title, so hovering them previews where they lead.
+func (r *Renderer) annotateIssueLinks(doc ast.Node) {
+	if r.lookup == nil {
+		return
+	}
+
+	links := make(map[string][]*ast.Link)
+	var identifiers []string
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		link, ok := n.(*ast.Link)
+		if !entering || !ok || len(link.Title) > 0 {
+			return ast.WalkContinue, nil
+		}
+		m := r.issuePathPattern.FindSubmatch(link.Destination)
+		if m == nil {
+			return ast.WalkContinue, nil
+		}
+		id := string(m[1])
+		if _, seen := links[id]; !seen {
+			identifiers = append(identifiers, id)
+		}
+		links[id] = append(links[id], link)
+		return ast.WalkContinue, nil
+	})
+	if len(identifiers) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), previewTimeout)
+	defer cancel()
+
+	issues, err := r.lookup.GetMany(ctx, identifiers)
+	if err != nil {
+		slog.Debug("skipping issue link previews", "error", err)
+		return
+	}
+	for id, issue := range issues {
+		if !r.gate.IsPublic(issue) {
+			continue
+		}
+		for _, link := range links[id] {
+			link.Title = []byte(issue.Title)
+		}
+	}
+}
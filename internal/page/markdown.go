@@ -0,0 +1,36 @@
+package page
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+)
+
+// RenderIssueMarkdown writes the issue as plain markdown for pasting into
+// other documents. The description is included verbatim.
+func (r *Renderer) RenderIssueMarkdown(w io.Writer, issue *linearapi.Issue) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s: %s\n\n", issue.Identifier, issue.Title)
+
+	fmt.Fprintf(&b, "- **State:** %s\n", issue.State.Name)
+	if len(issue.Labels) > 0 {
+		names := make([]string, len(issue.Labels))
+		for i, l := range issue.Labels {
+			names[i] = l.Name
+		}
+		fmt.Fprintf(&b, "- **Labels:** %s\n", strings.Join(names, ", "))
+	}
+	for _, pr := range issue.GitHubPRs(r.githubHosts...) {
+		fmt.Fprintf(&b, "- **Pull request:** [%s](%s)\n", pr.Title, pr.URL)
+	}
+
+	if desc := strings.TrimSpace(r.redact(issue.Description)); desc != "" {
+		fmt.Fprintf(&b, "\n%s\n", desc)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
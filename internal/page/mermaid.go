@@ -0,0 +1,82 @@
+package page
+
+import (
+	"io/fs"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+const mermaidContainer = `<div class="mermaid">`
+
+// mermaidBundle is the Mermaid build pages load diagrams with. It is vendored
+// rather than loaded from a CDN so no third-party code runs on issue pages;
+// `make vendor-mermaid` fetches the pinned version.
+const mermaidBundle = "static/mermaid.min.js"
+
+// MermaidVendored reports whether the Mermaid build is embedded, which
+// SetMermaid needs to be of any use.
+func MermaidVendored() bool {
+	_, err := fs.Stat(staticFS, mermaidBundle)
+	return err == nil
+}
+
+// SetMermaid turns ```mermaid blocks into containers that the Mermaid
+// script renders as diagrams in the browser.
+func (r *Renderer) SetMermaid(enabled bool) {
+	r.mermaid = enabled
+}
+
+// codeBlockRenderer replaces goldmark's fenced code block rendering so
+// mermaid blocks can be emitted as diagram containers. Other blocks render as
+// goldmark would, apart from the optional copy button.
+type codeBlockRenderer struct {
+	r *Renderer
+}
+
+func (c *codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, c.renderFencedCodeBlock)
+}
+
+func (c *codeBlockRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.FencedCodeBlock)
+	language := n.Language(source)
+
+	if c.r.mermaid && string(language) == "mermaid" {
+		_, _ = w.WriteString(mermaidContainer)
+		writeLines(w, source, n)
+		_, _ = w.WriteString("</div>\n")
+		return ast.WalkSkipChildren, nil
+	}
+
+	if c.r.copyButtons {
+		_, _ = w.WriteString(copyContainer + copyButton)
+	}
+	_, _ = w.WriteString("<pre><code")
+	if language != nil {
+		_, _ = w.WriteString(` class="language-`)
+		html.DefaultWriter.Write(w, language)
+		_ = w.WriteByte('"')
+	}
+	_ = w.WriteByte('>')
+	writeLines(w, source, n)
+	_, _ = w.WriteString("</code></pre>")
+	if c.r.copyButtons {
+		_, _ = w.WriteString("</div>")
+	}
+	_ = w.WriteByte('\n')
+	return ast.WalkSkipChildren, nil
+}
+
+func writeLines(w util.BufWriter, source []byte, n ast.Node) {
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		html.DefaultWriter.RawWrite(w, line.Value(source))
+	}
+}
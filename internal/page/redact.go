@@ -0,0 +1,29 @@
+package page
+
+import (
+	"regexp"
+)
+
+const redacted = "[redacted]"
+
+// DefaultRedactions catch credentials commonly pasted into issues by mistake.
+var DefaultRedactions = []*regexp.Regexp{
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),          // AWS access key ID
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{8,}=*`), // Authorization header value
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),         // GitHub token
+	regexp.MustCompile(`\blin_(?:api|oauth)_[A-Za-z0-9]{32,}\b`), // Linear API key
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+}
+
+// SetRedactions replaces text in descriptions matching any of patterns with
+// "[redacted]" before it is rendered or exported.
+func (r *Renderer) SetRedactions(patterns []*regexp.Regexp) {
+	r.redactions = patterns
+}
+
+func (r *Renderer) redact(s string) string {
+	for _, re := range r.redactions {
+		s = re.ReplaceAllLiteralString(s, redacted)
+	}
+	return s
+}
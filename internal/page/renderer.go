@@ -3,58 +3,117 @@ package page
 import (
 	"bytes"
 	"embed"
+	"fmt"
 	"html/template"
 	"io"
-	"io/fs"
-	"net/http"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
-	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 
 	"miren.dev/linear-issue-bridge/internal/linearapi"
 )
 
-//go:embed templates/*.html
+//go:embed templates/*.html templates/*.svg
 var templateFS embed.FS
 
 //go:embed static/*
 var staticFS embed.FS
 
-var md = goldmark.New(
-	goldmark.WithExtensions(
-		extension.GFM,
-	),
-	goldmark.WithRendererOptions(
-		html.WithUnsafe(),
-	),
-)
-
 type Renderer struct {
-	templates *template.Template
-	teamKey   string
+	templates        *template.Template
+	md               goldmark.Markdown
+	teamKey          string
+	issuePathPattern *regexp.Regexp
+	githubHosts      []string
+	lookup           IssueLookup
+	gate             linearapi.GateMode
+	assetDir         string
+	assetHost        string
+	mermaid          bool
+	copyButtons      bool
+	plainText        bool
+	linkSchemes      []string
+	redactions       []*regexp.Regexp
+
+	canonicalAttachment string
 }
 
 func NewRenderer(teamKey string, fathomSiteID string) (*Renderer, error) {
+	r := &Renderer{
+		teamKey:          teamKey,
+		issuePathPattern: regexp.MustCompile(`^/(` + regexp.QuoteMeta(strings.ToUpper(teamKey)) + `-\d+)$`),
+		linkSchemes:      DefaultLinkSchemes,
+		redactions:       DefaultRedactions,
+	}
+
+	r.md = goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+		),
+		goldmark.WithParserOptions(
+			parser.WithASTTransformers(
+				util.Prioritized(&linkTransformer{r: r}, 100),
+				util.Prioritized(&schemeFilter{r: r}, 200),
+			),
+		),
+		goldmark.WithRendererOptions(
+			renderer.WithNodeRenderers(util.Prioritized(&codeBlockRenderer{r: r}, 100)),
+		),
+	)
+
 	funcMap := template.FuncMap{
-		"markdown": renderMarkdown,
+		"markdown":     r.renderMarkdown,
+		"asset":        r.assetURL,
 		"fathomSiteID": func() string { return fathomSiteID },
+		"textColor":    textColor,
+		"lineY":        func(i int) int { return cardTitleTop + i*cardLineHeight },
 	}
 
-	tmpl, err := template.New("").Funcs(funcMap).ParseFS(templateFS, "templates/*.html")
+	tmpl, err := template.New("").Funcs(funcMap).ParseFS(templateFS, "templates/*.html", "templates/*.svg")
 	if err != nil {
 		return nil, err
 	}
+	r.templates = tmpl
+
+	return r, nil
+}
+
+// SetAssetHost makes pages load static assets and icons from host, such as
+// "https://cdn.example.com", instead of from this server, so a CDN can cache
+// them at the edge. This server still serves them as the CDN's origin.
+func (r *Renderer) SetAssetHost(host string) {
+	r.assetHost = strings.TrimRight(host, "/")
+}
 
-	return &Renderer{
-		templates: tmpl,
-		teamKey:   teamKey,
-	}, nil
+// assetURL is where pages load the asset this server serves at path.
+func (r *Renderer) assetURL(path string) string {
+	return r.assetHost + path
 }
 
-func (r *Renderer) StaticHandler() http.Handler {
-	sub, _ := fs.Sub(staticFS, "static")
-	return http.FileServerFS(sub)
+// SetGitHubHosts adds GitHub Enterprise hosts whose pull request links are
+// shown alongside github.com ones.
+func (r *Renderer) SetGitHubHosts(hosts []string) {
+	r.githubHosts = hosts
+}
+
+// SetGateMode sets which issues count as public for hover previews.
+func (r *Renderer) SetGateMode(m linearapi.GateMode) {
+	r.gate = m
+}
+
+// SetIssueLookup enables hover previews on links to other issues in
+// descriptions.
+func (r *Renderer) SetIssueLookup(lookup IssueLookup) {
+	r.lookup = lookup
 }
 
 func (r *Renderer) RenderIndexPage(w io.Writer) error {
@@ -64,17 +123,63 @@ func (r *Renderer) RenderIndexPage(w io.Writer) error {
 type issuePageData struct {
 	Issue           *linearapi.Issue
 	DescriptionHTML template.HTML
+	Comments        []commentData
 	GitHubPRs       []linearapi.Attachment
+	PRSummary       string
+	OutdatedAsOf    time.Time
 	TeamKey         string
+	Canonical       string
+	Mermaid         bool
+	CopyButtons     bool
+}
+
+type commentData struct {
+	Author    string
+	CreatedAt time.Time
+	BodyHTML  template.HTML
 }
 
 func (r *Renderer) RenderIssuePage(w io.Writer, issue *linearapi.Issue) error {
-	descHTML := renderMarkdown(issue.Description)
+	return r.RenderIssuePageAsOf(w, issue, time.Time{})
+}
+
+// RenderIssuePageAsOf renders the issue with a banner warning that it may be
+// outdated, as of when it was fetched. A zero asOf renders no banner.
+func (r *Renderer) RenderIssuePageAsOf(w io.Writer, issue *linearapi.Issue, asOf time.Time) error {
+	descHTML := r.renderMarkdown(issue.Description)
+	bodies := string(descHTML)
+	comments := make([]commentData, len(issue.Comments))
+	for i, c := range issue.Comments {
+		comments[i] = commentData{Author: c.Author, CreatedAt: c.CreatedAt, BodyHTML: r.renderMarkdown(c.Body)}
+		bodies += string(comments[i].BodyHTML)
+	}
+	prs := issue.GitHubPRs(r.githubHosts...)
+	var prSummary string
+	if s, ok := linearapi.SummarizePRs(prs); ok {
+		prSummary = s.String()
+	}
 	return r.templates.ExecuteTemplate(w, "issue.html", issuePageData{
 		Issue:           issue,
 		DescriptionHTML: descHTML,
-		GitHubPRs:       issue.GitHubPRs(),
+		Comments:        comments,
+		GitHubPRs:       prs,
+		PRSummary:       prSummary,
+		OutdatedAsOf:    asOf,
 		TeamKey:         r.teamKey,
+		Canonical:       r.canonicalURL(issue),
+		Mermaid:         r.mermaid && strings.Contains(bodies, mermaidContainer),
+		CopyButtons:     r.copyButtons && strings.Contains(bodies, copyContainer),
+	})
+}
+
+// RenderPreviewPage renders markdown the way an issue description would be,
+// on a placeholder issue page, so a description can be checked before the
+// issue is published.
+func (r *Renderer) RenderPreviewPage(w io.Writer, markdown string) error {
+	return r.RenderIssuePage(w, &linearapi.Issue{
+		Identifier:  r.teamKey + "-0",
+		Title:       "Preview",
+		Description: markdown,
 	})
 }
 
@@ -94,10 +199,83 @@ func (r *Renderer) RenderNotFound(w io.Writer) error {
 	return r.templates.ExecuteTemplate(w, "notfound.html", nil)
 }
 
-func renderMarkdown(src string) template.HTML {
-	var buf bytes.Buffer
-	if err := md.Convert([]byte(src), &buf); err != nil {
-		return template.HTML("<p>" + template.HTMLEscapeString(src) + "</p>")
+// SetPlainText shows descriptions as escaped preformatted text instead of
+// rendering them as markdown, for teams that don't write markdown or don't
+// want to expose the renderer to untrusted input.
+func (r *Renderer) SetPlainText(enabled bool) {
+	r.plainText = enabled
+}
+
+func (r *Renderer) renderMarkdown(src string) template.HTML {
+	src = r.redact(src)
+	if r.plainText {
+		return plainHTML(src)
+	}
+	source := []byte(src)
+	doc := r.md.Parser().Parse(text.NewReader(source))
+	r.annotateIssueLinks(doc)
+
+	// Top-level blocks render one at a time so that a construct goldmark
+	// chokes on costs only its own formatting, not the whole description's.
+	var buf, block bytes.Buffer
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		block.Reset()
+		if err := r.renderBlock(&block, source, n); err != nil {
+			snippet := blockSource(n, source)
+			slog.Warn("markdown block failed to render", "kind", n.Kind().String(), "snippet", truncate(snippet, 80), "error", err)
+			buf.WriteString(string(plainHTML(snippet)))
+			continue
+		}
+		buf.Write(block.Bytes())
 	}
 	return template.HTML(buf.String())
 }
+
+func (r *Renderer) renderBlock(w io.Writer, source []byte, n ast.Node) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return r.md.Renderer().Render(w, source, n)
+}
+
+// blockSource is the source text spanned by a block's lines, including those
+// of nested blocks. Container markers such as "> " at the start of the block
+// are not included.
+func blockSource(n ast.Node, source []byte) string {
+	start, stop := -1, -1
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || c.Type() != ast.TypeBlock {
+			return ast.WalkContinue, nil
+		}
+		lines := c.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			seg := lines.At(i)
+			if start < 0 || seg.Start < start {
+				start = seg.Start
+			}
+			stop = max(stop, seg.Stop)
+		}
+		return ast.WalkContinue, nil
+	})
+	if start < 0 {
+		return ""
+	}
+	return string(source[start:stop])
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
+func plainHTML(src string) template.HTML {
+	if src == "" {
+		return ""
+	}
+	return template.HTML(`<pre class="plain-text">` + template.HTMLEscapeString(src) + "</pre>")
+}
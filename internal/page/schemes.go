@@ -0,0 +1,82 @@
+package page
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// DefaultLinkSchemes are the URL schemes links in descriptions may use.
+var DefaultLinkSchemes = []string{"https", "http", "mailto"}
+
+// SetLinkSchemes limits markdown links in descriptions to the given URL
+// schemes. Links using any other scheme are rendered as plain text. Relative
+// links are always kept. Raw HTML in descriptions is never rendered, so it
+// can't carry links past the check.
+func (r *Renderer) SetLinkSchemes(schemes []string) {
+	r.linkSchemes = schemes
+}
+
+func (r *Renderer) linkAllowed(dest string) bool {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		return true
+	}
+	return slices.ContainsFunc(r.linkSchemes, func(s string) bool {
+		return strings.EqualFold(s, u.Scheme)
+	})
+}
+
+// schemeFilter unwraps links whose scheme isn't allowed. goldmark's own
+// check only catches a few dangerous schemes, such as javascript:, so it
+// doesn't enforce an allowlist.
+type schemeFilter struct {
+	r *Renderer
+}
+
+func (f *schemeFilter) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	source := reader.Source()
+
+	var blocked []ast.Node
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			if !f.r.linkAllowed(string(n.Destination)) {
+				blocked = append(blocked, n)
+			}
+		case *ast.AutoLink:
+			dest := string(n.URL(source))
+			if n.AutoLinkType == ast.AutoLinkEmail {
+				dest = "mailto:" + dest
+			}
+			if !f.r.linkAllowed(dest) {
+				blocked = append(blocked, n)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, n := range blocked {
+		parent := n.Parent()
+		if al, ok := n.(*ast.AutoLink); ok {
+			parent.ReplaceChild(parent, n, ast.NewString(al.Label(source)))
+			continue
+		}
+		for c := n.FirstChild(); c != nil; {
+			next := c.NextSibling()
+			parent.InsertBefore(parent, n, c)
+			c = next
+		}
+		parent.RemoveChild(parent, n)
+	}
+}
@@ -0,0 +1,89 @@
+package page
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// compressible lists the static file types worth gzipping; images other than
+// SVG are already compressed.
+var compressible = map[string]bool{".css": true, ".js": true, ".svg": true}
+
+// StaticHandler serves the embedded static files. Text assets are gzipped
+// once when the handler is built and served compressed to clients that
+// accept it.
+func (r *Renderer) StaticHandler() http.Handler {
+	sub, _ := fs.Sub(staticFS, "static")
+	return &staticHandler{
+		files:   http.FileServerFS(sub),
+		gzipped: gzipFiles(sub),
+	}
+}
+
+type staticHandler struct {
+	files   http.Handler
+	gzipped map[string][]byte // file name -> gzipped contents
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	gz, ok := h.gzipped[r.URL.Path]
+	if !ok {
+		h.files.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		h.files.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(r.URL.Path)))
+	w.Header().Set("Content-Encoding", "gzip")
+	http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(gz))
+}
+
+// gzipFiles compresses the compressible files at the top of fsys, skipping
+// any that gzip doesn't make smaller.
+func gzipFiles(fsys fs.FS) map[string][]byte {
+	entries, _ := fs.ReadDir(fsys, ".")
+	gzipped := make(map[string][]byte)
+	for _, e := range entries {
+		if e.IsDir() || !compressible[path.Ext(e.Name())] {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			continue
+		}
+		var buf bytes.Buffer
+		zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		zw.Write(data)
+		zw.Close()
+		if buf.Len() < len(data) {
+			gzipped[e.Name()] = buf.Bytes()
+		}
+	}
+	return gzipped
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if c := strings.TrimSpace(coding); c != "gzip" && c != "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, _ = strconv.ParseFloat(v, 64)
+		}
+		return q > 0
+	}
+	return false
+}
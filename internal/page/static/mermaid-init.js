@@ -0,0 +1,8 @@
+// Loaded only on pages with diagrams, after the vendored Mermaid build
+// (mermaid.min.js, see `make vendor-mermaid`) has defined the mermaid global,
+// so its sizeable bundle isn't fetched for every issue.
+const { mermaid } = globalThis;
+
+const dark = window.matchMedia("(prefers-color-scheme: dark)").matches;
+mermaid.initialize({ startOnLoad: false, theme: dark ? "dark" : "default" });
+await mermaid.run({ querySelector: ".mermaid" });
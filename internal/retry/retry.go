@@ -0,0 +1,82 @@
+// Package retry retries calls to remote APIs that fail transiently.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Policy is how many times to retry a failed call and how long to wait
+// before the first retry. The wait doubles on each later attempt, and each
+// wait is shortened by a random amount of up to half, so that clients that
+// failed together don't all retry together.
+type Policy struct {
+	MaxRetries int
+	Base       time.Duration
+}
+
+// Default retries three times, waiting up to 500ms, 1s, then 2s.
+var Default = Policy{MaxRetries: 3, Base: 500 * time.Millisecond}
+
+// Do calls fn until it succeeds, reports the error as permanent, or the
+// retries run out, and returns fn's last error. fn returns retryable=true for
+// failures worth another attempt, such as dropped connections or 5xx
+// responses. Do never waits past ctx's deadline: if the next attempt couldn't
+// start before it, Do gives up at once.
+func (p Policy) Do(ctx context.Context, fn func() (retryable bool, err error)) error {
+	wait := p.Base
+	for attempt := 0; ; attempt++ {
+		retryable, err := fn()
+		if err == nil || !retryable || attempt >= p.MaxRetries {
+			return err
+		}
+
+		d := jitter(wait)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+			return err
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		wait *= 2
+	}
+}
+
+// jitter picks a wait between half of d and d.
+func jitter(d time.Duration) time.Duration {
+	if half := d / 2; half > 0 {
+		return d - rand.N(half)
+	}
+	return d
+}
+
+// Status reports whether an HTTP response status is worth retrying: rate
+// limiting and server errors that are usually temporary.
+func Status(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Network reports whether an error from sending an HTTP request is a
+// network failure worth retrying, such as a failed dial or a connection
+// dropped before the response arrived.
+func Network(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
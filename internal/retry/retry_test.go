@@ -0,0 +1,151 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDoRetriesUntilSuccess(t *testing.T) {
+	p := Policy{MaxRetries: 3, Base: time.Millisecond}
+	calls := 0
+	err := p.Do(context.Background(), func() (bool, error) {
+		calls++
+		if calls < 3 {
+			return true, errors.New("temporary")
+		}
+		return false, nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestDoGivesUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    Policy
+		retryable bool
+		wantCalls int
+	}{
+		{"retries exhausted", Policy{MaxRetries: 2, Base: time.Millisecond}, true, 3},
+		{"no retries", Policy{}, true, 1},
+		{"permanent error", Policy{MaxRetries: 2, Base: time.Millisecond}, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			want := errors.New("failed")
+			err := tt.policy.Do(context.Background(), func() (bool, error) {
+				calls++
+				return tt.retryable, want
+			})
+			if err != want {
+				t.Errorf("err = %v, want %v", err, want)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoStopsBeforeDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	p := Policy{MaxRetries: 5, Base: time.Second}
+	calls := 0
+	start := time.Now()
+	err := p.Do(ctx, func() (bool, error) {
+		calls++
+		return true, errors.New("temporary")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("Do took %v; it should give up without waiting when the retry can't start before the deadline", elapsed)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for range 100 {
+		if d := jitter(time.Second); d <= 500*time.Millisecond || d > time.Second {
+			t.Fatalf("jitter(1s) = %v, want in (500ms, 1s]", d)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	for code, want := range map[int]bool{
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+		http.StatusServiceUnavailable:  true,
+		http.StatusGatewayTimeout:      true,
+		http.StatusNotImplemented:      false,
+		http.StatusBadRequest:          false,
+		http.StatusNotFound:            false,
+	} {
+		if got := Status(code); got != want {
+			t.Errorf("Status(%d) = %t, want %t", code, got, want)
+		}
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	// Dial a port nothing listens on.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	_, dialErr := http.Get("http://" + addr)
+	if dialErr == nil {
+		t.Fatal("expected dial error")
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"refused dial", dialErr, true},
+		{"dropped connection", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"other", errors.New("bad request"), false},
+	}
+	for _, tt := range tests {
+		if got := Network(tt.err); got != tt.want {
+			t.Errorf("%s: Network(%v) = %t, want %t", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestDoStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Policy{MaxRetries: 5, Base: time.Hour}
+	calls := 0
+	err := p.Do(ctx, func() (bool, error) {
+		calls++
+		cancel()
+		return true, errors.New("temporary")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
@@ -5,13 +5,11 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
-	"net/http"
 	"os"
-	"regexp"
-	"strings"
 	"time"
 
 	"miren.dev/linear-issue-bridge/internal/cache"
+	"miren.dev/linear-issue-bridge/internal/cache/redisstore"
 	"miren.dev/linear-issue-bridge/internal/github"
 	"miren.dev/linear-issue-bridge/internal/linearapi"
 	"miren.dev/linear-issue-bridge/internal/page"
@@ -24,107 +22,131 @@ func main() {
 	}
 }
 
+// warmTimeout bounds how long startup waits for CACHE_WARM fetches.
+const warmTimeout = 30 * time.Second
+
 func run() error {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
 	}
-
-	apiKey := os.Getenv("LINEAR_API_KEY")
-	if apiKey == "" {
-		return fmt.Errorf("LINEAR_API_KEY is required")
+	slog.Info("effective config", "config", cfg)
+	if cfg.GateMode == linearapi.GateDenylist {
+		slog.Warn("GATE_MODE=denylist: every issue is public unless labeled private or confidential")
 	}
 
-	teamKey := os.Getenv("LINEAR_TEAM_KEY")
-	if teamKey == "" {
-		return fmt.Errorf("LINEAR_TEAM_KEY is required")
+	client := cfg.NewClient()
+	publicLabel := linearapi.NewLabelResolver(client, cfg.TeamKey, cfg.PublicLabel)
+	publicLabel.SetColor(cfg.PublicLabelColor)
+	// Only pages show comments, so only the page cache fetches them.
+	var pageFetcher cache.IssueFetcher = client
+	if cfg.Comments {
+		pageFetcher = client.WithComments()
 	}
-
-	client := linearapi.NewClient(apiKey)
-	issueCache := cache.New(client, cache.DefaultTTL)
-
-	fathomSiteID := os.Getenv("FATHOM_SITE_ID")
-
-	renderer, err := page.NewRenderer(teamKey, fathomSiteID)
+	issueCache := cache.New(pageFetcher, cfg.CacheTTL)
+	if cfg.CacheRedisURL != "" {
+		store, err := redisstore.New(cfg.CacheRedisURL)
+		if err != nil {
+			return fmt.Errorf("configure redis cache: %w", err)
+		}
+		issueCache = cache.NewWithStore(pageFetcher, cfg.CacheTTL, store)
+	}
+	issueCache.SetNegativeTTL(cfg.CacheNegativeTTL)
+	issueCache.SetStateTTLs(cfg.CacheStateTTLs)
+	issueCache.SetStaleTTL(cfg.CacheStaleTTL)
+	issueCache.SetHedgeDelay(cfg.CacheHedgeDelay)
+	issueCache.SetMaxAge(cfg.CacheMaxAge)
+	issueCache.SetMaxEntries(cfg.CacheMaxEntries)
+	issueCache.SetFetchLimit(cfg.MaxFetches, cfg.FetchQueueWait)
+	issueCache.RefreshHot(context.Background(), cfg.HotIssues, cfg.HotRefresh, cfg.HotJitter)
+
+	renderer, err := page.NewRenderer(cfg.TeamKey, cfg.FathomSiteID)
 	if err != nil {
 		return fmt.Errorf("initialize renderer: %w", err)
 	}
-
-	identifierPattern := regexp.MustCompile(`^` + regexp.QuoteMeta(strings.ToUpper(teamKey)) + `-\d+$`)
-
-	mux := http.NewServeMux()
-
-	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprint(w, "ok")
-	})
-
-	mux.Handle("GET /static/", http.StripPrefix("/static/", renderer.StaticHandler()))
-
-	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
-		if err := renderer.RenderIndexPage(w); err != nil {
-			slog.Error("render index", "error", err)
-		}
-	})
-
-	mux.HandleFunc("GET /{identifier}", func(w http.ResponseWriter, r *http.Request) {
-		identifier := strings.ToUpper(r.PathValue("identifier"))
-
-		if !identifierPattern.MatchString(identifier) {
-			w.WriteHeader(http.StatusNotFound)
-			if err := renderer.RenderNotFound(w); err != nil {
-				slog.Error("render not found", "error", err)
-			}
-			return
+	renderer.SetGitHubHosts(cfg.GitHubHosts)
+	renderer.SetIssueLookup(issueCache)
+	renderer.SetGateMode(cfg.GateMode)
+	renderer.SetAssetDir(cfg.AssetDir)
+	renderer.SetAssetHost(cfg.AssetHost)
+	renderer.SetMermaid(cfg.Mermaid)
+	renderer.SetCopyButtons(cfg.CopyButtons)
+	renderer.SetCanonicalAttachment(cfg.CanonicalAttachment)
+	renderer.SetPlainText(!cfg.RenderMarkdown)
+	renderer.SetLinkSchemes(cfg.LinkSchemes)
+	renderer.SetRedactions(cfg.Redactions)
+
+	srv := &server{
+		cache:             issueCache,
+		publicIssues:      cache.NewPublicList(client, cfg.TeamKey, cfg.GateMode, feedSize, cfg.PublicListTTL),
+		renderer:          renderer,
+		teamKey:           cfg.TeamKey,
+		identifierPattern: newIdentifierPattern(cfg.teamKeys()...),
+		bareNumberTeam:    bareNumberTeam(cfg.BareNumbers, cfg.TeamKey),
+		aliases:           cfg.Aliases,
+		staleBanner:       cfg.StaleBanner,
+		canonicalRedirect: cfg.CanonicalRedirect,
+		feedContent:       cfg.FeedContent,
+		listTimeout:       cfg.ListTimeout,
+		gate:              cfg.GateMode,
+		adminToken:        cfg.AdminToken,
+		dev:               cfg.Dev,
+	}
+	mux := srv.routes()
+
+	switch {
+	case cfg.GateMode == linearapi.GateDenylist:
+		slog.Info("github webhook disabled (public labels are unused in denylist mode)")
+	case cfg.WebhookSecret != "":
+		labeler := linearapi.NewPublicLabeler(client, cfg.TeamKey)
+		labeler.SetLabelResolver(publicLabel)
+		labeler.SetSkipInProgress(cfg.SkipInProgress)
+		labeler.SetTeamKeys(cfg.teamKeys()...)
+		webhookHandler := github.NewWebhookHandler(cfg.WebhookSecret, cfg.TeamKey, labeler)
+		webhookHandler.SetLabelTimeout(cfg.LabelTimeout)
+		webhookHandler.SetAsync(cfg.WebhookAsync)
+		if cfg.WebhookAsync {
+			webhookHandler.SetAsyncQueue(cfg.WebhookQueueSize, cfg.WebhookWorkers)
 		}
-
-		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-		defer cancel()
-
-		issue, err := issueCache.Get(ctx, identifier)
-		if err != nil {
-			slog.Error("fetch issue", "identifier", identifier, "error", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
+		webhookHandler.SetMinNumbers(cfg.MinNumbers)
+		webhookHandler.SetCache(issueCache)
+		webhookHandler.SetPublishDelay(cfg.PublishDelay)
+		if cfg.VerifyPayloads {
+			webhookHandler.SetVerifier(github.NewVerifier(github.ResolveToken()))
 		}
-
-		if issue == nil {
-			w.WriteHeader(http.StatusNotFound)
-			if err := renderer.RenderNotFound(w); err != nil {
-				slog.Error("render not found", "error", err)
-			}
-			return
+		if cfg.RepoTeams != nil {
+			webhookHandler.SetRepoTeams(cfg.RepoTeams)
 		}
-
-		if !issue.HasLabel("public") {
-			w.WriteHeader(http.StatusOK)
-			if err := renderer.RenderStubPage(w, identifier); err != nil {
-				slog.Error("render stub", "error", err)
-			}
-			return
+		if cfg.UnpublishReverts {
+			webhookHandler.SetUnpublisher(labeler)
 		}
-
-		slog.Info("serving issue", "identifier", identifier)
-		w.WriteHeader(http.StatusOK)
-		if err := renderer.RenderIssuePage(w, issue); err != nil {
-			slog.Error("render issue", "error", err)
-		}
-	})
-
-	webhookSecret := os.Getenv("GITHUB_WEBHOOK_SECRET")
-	if webhookSecret != "" {
-		labeler := linearapi.NewPublicLabeler(client, teamKey)
-		webhookHandler := github.NewWebhookHandler(webhookSecret, teamKey, labeler)
 		mux.Handle("POST /webhook/github", webhookHandler)
 		slog.Info("github webhook enabled", "path", "/webhook/github")
-	} else {
+	default:
 		slog.Info("github webhook disabled (GITHUB_WEBHOOK_SECRET not set)")
 	}
 
-	ln, err := net.Listen("tcp", ":"+port)
+	if cfg.LinearWebhookSecret != "" {
+		linearHandler := linearapi.NewWebhookHandler(cfg.LinearWebhookSecret, cfg.TeamKey, issueCache)
+		linearHandler.SetTeamKeys(cfg.teamKeys()...)
+		linearHandler.SetPublicLabel(cfg.PublicLabel)
+		if cfg.UnpublishPrivate && cfg.GateMode == linearapi.GateAllowlist {
+			linearHandler.SetUnpublisher(linearapi.NewPublicLabeler(client, cfg.TeamKey))
+		}
+		mux.Handle("POST /webhook/linear", linearHandler)
+		slog.Info("linear webhook enabled", "path", "/webhook/linear")
+	}
+
+	if len(cfg.CacheWarm) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), warmTimeout)
+		issueCache.Warm(ctx, cfg.CacheWarm)
+		cancel()
+	}
+
+	ln, err := net.Listen("tcp", ":"+cfg.Port)
 	if err != nil {
 		return fmt.Errorf("listen: %w", err)
 	}
-	slog.Info("starting server", "addr", "http://"+ln.Addr().String(), "team_key", teamKey)
-	return http.Serve(ln, mux)
+	slog.Info("starting server", "addr", "http://"+ln.Addr().String(), "team_key", cfg.TeamKey)
+	return newHTTPServer(cfg.HTTP, mux).Serve(ln)
 }
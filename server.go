@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/cache"
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+	"miren.dev/linear-issue-bridge/internal/page"
+)
+
+type server struct {
+	cache             *cache.Cache
+	publicIssues      *cache.PublicList
+	renderer          *page.Renderer
+	teamKey           string
+	identifierPattern *regexp.Regexp
+	bareNumberTeam    string
+	aliases           map[string]string // old identifier -> where the issue lives now
+	staleBanner       time.Duration
+	canonicalRedirect bool // send readers to an issue's canonical attachment link
+	feedContent       page.FeedContent
+	listTimeout       time.Duration // bounds fetching the list behind /feed.json
+	gate              linearapi.GateMode
+	adminToken        string
+	dev               bool // serve development-only endpoints like /preview
+}
+
+// maxPreviewBody bounds the markdown accepted by /preview.
+const maxPreviewBody = 1 << 20 // 1 MB
+
+// feedSize is how many issues the feed lists.
+const feedSize = 50
+
+// issueTimeout bounds fetching one issue for a page. defaultListTimeout, the
+// default LIST_TIMEOUT, is longer because lists fetch many issues at once.
+const (
+	issueTimeout       = 10 * time.Second
+	defaultListTimeout = 30 * time.Second
+)
+
+var bareNumberPattern = regexp.MustCompile(`^[1-9]\d*$`)
+
+// bareNumberTeam is the team a bare issue number like /42 refers to: the only
+// configured team, if enabled. With several teams a bare number is ambiguous,
+// so none is chosen.
+func bareNumberTeam(enabled bool, teamKeys ...string) string {
+	if !enabled || len(teamKeys) != 1 {
+		return ""
+	}
+	return strings.ToUpper(teamKeys[0])
+}
+
+// newIdentifierPattern matches identifiers for any of teamKeys, anchored so
+// that one key can't match as a prefix or suffix of another (MIR vs MIRA).
+// Linear numbers issues from 1 without padding, so a leading zero is
+// rejected rather than serving MIR-01 as a duplicate of MIR-1.
+func newIdentifierPattern(teamKeys ...string) *regexp.Regexp {
+	quoted := make([]string, len(teamKeys))
+	for i, k := range teamKeys {
+		quoted[i] = regexp.QuoteMeta(strings.ToUpper(k))
+	}
+	return regexp.MustCompile(`^(?:` + strings.Join(quoted, "|") + `)-[1-9]\d*$`)
+}
+
+func newHTTPServer(hc httpConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: hc.ReadHeaderTimeout,
+		ReadTimeout:       hc.ReadTimeout,
+		WriteTimeout:      hc.WriteTimeout,
+		IdleTimeout:       hc.IdleTimeout,
+		MaxHeaderBytes:    hc.MaxHeaderBytes,
+	}
+}
+
+func (s *server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "ok")
+	})
+
+	mux.Handle("GET /static/", http.StripPrefix("/static/", s.renderer.StaticHandler()))
+
+	icons := s.renderer.IconHandler()
+	mux.Handle("GET /favicon.ico", icons)
+	mux.Handle("GET /favicon.svg", icons)
+	mux.Handle("GET /apple-touch-icon.png", icons)
+
+	mux.HandleFunc("GET /feed.json", s.handleJSONFeed)
+
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		if err := s.renderer.RenderIndexPage(w); err != nil {
+			slog.Error("render index", "error", err)
+		}
+	})
+
+	if s.adminToken != "" {
+		mux.Handle("GET /admin/cache", s.requireAdmin(http.HandlerFunc(s.handleAdminCache)))
+		mux.Handle("GET /admin/stats", s.requireAdmin(http.HandlerFunc(s.handleAdminStats)))
+		mux.Handle("DELETE /admin/cache", s.requireAdmin(http.HandlerFunc(s.handleAdminCacheClear)))
+		mux.Handle("DELETE /admin/cache/{identifier}", s.requireAdmin(http.HandlerFunc(s.handleAdminCacheDelete)))
+	}
+
+	if s.dev {
+		mux.HandleFunc("POST /preview", s.handlePreview)
+	}
+
+	// GET patterns also match HEAD; handleIssue takes care of not writing a body.
+	// It also serves /{identifier}.md and the /{identifier}.svg social card,
+	// since a /{identifier}/... route would collide with /static/.
+	mux.HandleFunc("GET /{identifier}", s.handleIssue)
+
+	// Anything else falls through to here, including /{identifier}/ with a
+	// trailing slash, which can't be routed directly for the same reason.
+	mux.HandleFunc("GET /", s.handleUnmatched)
+
+	return mux
+}
+
+// handleUnmatched redirects issue URLs copied with a trailing slash to the
+// canonical URL and serves the not found page for everything else.
+func (s *server) handleUnmatched(w http.ResponseWriter, r *http.Request) {
+	seg, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if ok && !strings.Contains(seg, "/") && s.identifierPattern.MatchString(strings.ToUpper(seg)) {
+		target := "/" + seg
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+	s.notFound(w, r)
+}
+
+func (s *server) handleIssue(w http.ResponseWriter, r *http.Request) {
+	identifier := strings.ToUpper(r.PathValue("identifier"))
+	identifier, asMarkdown := strings.CutSuffix(identifier, ".MD")
+	identifier, asCard := strings.CutSuffix(identifier, ".SVG")
+
+	if s.bareNumberTeam != "" && bareNumberPattern.MatchString(identifier) {
+		target := "/" + s.bareNumberTeam + "-" + identifier + strings.TrimPrefix(r.PathValue("identifier"), identifier)
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+
+	if alias, ok := s.aliases[identifier]; ok {
+		raw := r.PathValue("identifier")
+		target := "/" + alias + raw[len(identifier):]
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+
+	if !s.identifierPattern.MatchString(identifier) {
+		s.notFound(w, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), issueTimeout)
+	defer cancel()
+
+	issue, meta, err := s.cache.GetWithMeta(ctx, identifier)
+	if errors.Is(err, cache.ErrBusy) {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Server busy, try again shortly", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		slog.Error("fetch issue", "identifier", identifier, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("X-Cache", string(meta.Status))
+
+	if issue == nil {
+		s.notFound(w, r)
+		return
+	}
+
+	var buf bytes.Buffer
+	if asMarkdown {
+		if !s.gate.IsPublic(issue) {
+			http.Error(w, "Issue is not shared publicly", http.StatusNotFound)
+			return
+		}
+		if err := s.renderer.RenderIssueMarkdown(&buf, issue); err != nil {
+			slog.Error("render markdown", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeBody(w, r, http.StatusOK, "text/markdown; charset=utf-8", &buf)
+		return
+	}
+
+	if asCard {
+		if !s.gate.IsPublic(issue) {
+			http.Error(w, "Issue is not shared publicly", http.StatusNotFound)
+			return
+		}
+		if err := s.renderer.RenderCardSVG(&buf, issue); err != nil {
+			slog.Error("render card", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeBody(w, r, http.StatusOK, "image/svg+xml", &buf)
+		return
+	}
+
+	if !s.gate.IsPublic(issue) {
+		if err := s.renderer.RenderStubPage(&buf, identifier); err != nil {
+			slog.Error("render stub", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeHTML(w, r, http.StatusOK, &buf)
+		return
+	}
+
+	if s.canonicalRedirect {
+		if target, ok := s.renderer.CanonicalOverride(issue); ok {
+			http.Redirect(w, r, target, http.StatusFound)
+			return
+		}
+	}
+
+	var asOf time.Time
+	if s.staleBanner > 0 && time.Since(meta.FetchedAt) > s.staleBanner {
+		asOf = meta.FetchedAt
+	}
+	if err := s.renderer.RenderIssuePageAsOf(&buf, issue, asOf); err != nil {
+		slog.Error("render issue", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// The page depends on more than the issue's UpdatedAt, such as linked
+	// issues' titles, comments, and rendering settings, so the ETag covers
+	// what was rendered.
+	etag := pageETag(buf.Bytes())
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if r.Method != http.MethodHead {
+		slog.Info("serving issue", "identifier", identifier)
+	}
+	writeHTML(w, r, http.StatusOK, &buf)
+}
+
+func (s *server) handleJSONFeed(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.listTimeout)
+	defer cancel()
+
+	issues, err := s.publicIssues.Issues(ctx)
+	if err != nil {
+		slog.Error("fetch feed issues", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if notModified(w, r, lastModified(issues)) {
+		return
+	}
+
+	content := s.feedContent
+	if r.URL.Query().Get("summary") == "1" {
+		content = page.FeedExcerpt
+	}
+	var buf bytes.Buffer
+	if err := s.renderer.RenderJSONFeed(&buf, baseURL(r), issues, content); err != nil {
+		slog.Error("render json feed", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeBody(w, r, http.StatusOK, "application/feed+json", &buf)
+}
+
+// lastModified is when the most recently updated of issues changed, or the
+// zero time if there are none.
+func lastModified(issues []*linearapi.Issue) time.Time {
+	var latest time.Time
+	for _, issue := range issues {
+		if issue.UpdatedAt.After(latest) {
+			latest = issue.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// notModified sets Last-Modified and, if the client's copy is at least as
+// new, answers 304 and reports true. HTTP dates have one-second precision, so
+// modTime is truncated before comparing.
+func notModified(w http.ResponseWriter, r *http.Request, modTime time.Time) bool {
+	if modTime.IsZero() {
+		return false
+	}
+	modTime = modTime.UTC().Truncate(time.Second)
+	w.Header().Set("Last-Modified", modTime.Format(http.TimeFormat))
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modTime.After(since) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// baseURL is the scheme and host the request was made to, honoring the
+// X-Forwarded-Proto header set by the TLS-terminating proxy in front of us.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// requireAdmin only lets through requests bearing the admin token.
+func (s *server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type statsJSON struct {
+	Hits        int64      `json:"hits"`
+	Misses      int64      `json:"misses"`
+	Stale       int64      `json:"stale"`
+	Entries     int        `json:"entries"`
+	Evictions   int64      `json:"evictions"`
+	Coalesced   int64      `json:"coalesced"`
+	LastSuccess *time.Time `json:"last_success"`
+}
+
+func (s *server) handleAdminStats(w http.ResponseWriter, r *http.Request) {
+	stats := s.cache.Stats()
+	resp := statsJSON{
+		Hits:      stats.Hits,
+		Misses:    stats.Misses,
+		Stale:     stats.Stale,
+		Entries:   stats.Entries,
+		Evictions: stats.Evictions,
+		Coalesced: stats.Coalesced,
+	}
+	if !stats.LastSuccess.IsZero() {
+		resp.LastSuccess = &stats.LastSuccess
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("encode cache stats", "error", err)
+	}
+}
+
+// handleAdminCacheDelete drops one issue from the cache so the next request
+// refetches it.
+func (s *server) handleAdminCacheDelete(w http.ResponseWriter, r *http.Request) {
+	identifier := strings.ToUpper(r.PathValue("identifier"))
+	s.cache.Delete(identifier)
+	slog.Info("cache entry deleted by admin", "identifier", identifier)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePreview renders the markdown in the request body as an issue page
+// would, for checking a description before labeling the issue public.
+func (s *server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPreviewBody))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	if err := s.renderer.RenderPreviewPage(w, string(body)); err != nil {
+		slog.Error("render preview", "error", err)
+	}
+}
+
+func (s *server) handleAdminCacheClear(w http.ResponseWriter, r *http.Request) {
+	s.cache.Clear()
+	slog.Info("cache cleared by admin")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type cacheEntryJSON struct {
+	Identifier string  `json:"identifier"`
+	AgeSeconds float64 `json:"age_seconds"`
+	Found      bool    `json:"found"`
+	Public     bool    `json:"public"`
+}
+
+func (s *server) handleAdminCache(w http.ResponseWriter, r *http.Request) {
+	snapshot := s.cache.Snapshot()
+	entries := make([]cacheEntryJSON, len(snapshot))
+	for i, e := range snapshot {
+		entries[i] = cacheEntryJSON{
+			Identifier: e.Identifier,
+			AgeSeconds: e.Age.Seconds(),
+			Found:      e.Issue != nil,
+			Public:     e.Issue != nil && s.gate.IsPublic(e.Issue),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		slog.Error("encode cache snapshot", "error", err)
+	}
+}
+
+func (s *server) notFound(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := s.renderer.RenderNotFound(&buf); err != nil {
+		slog.Error("render not found", "error", err)
+	}
+	writeHTML(w, r, http.StatusNotFound, &buf)
+}
+
+func writeHTML(w http.ResponseWriter, r *http.Request, status int, buf *bytes.Buffer) {
+	writeBody(w, r, status, "text/html; charset=utf-8", buf)
+}
+
+// writeBody sends a fully rendered response so HEAD requests carry the same
+// Content-Length as the equivalent GET.
+func writeBody(w http.ResponseWriter, r *http.Request, status int, contentType string, buf *bytes.Buffer) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", fmt.Sprint(buf.Len()))
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		w.Write(buf.Bytes())
+	}
+}
+
+// pageETag is a strong ETag for a rendered page.
+func pageETag(page []byte) string {
+	sum := sha256.Sum256(page)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
@@ -0,0 +1,925 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"miren.dev/linear-issue-bridge/internal/cache"
+	"miren.dev/linear-issue-bridge/internal/linearapi"
+	"miren.dev/linear-issue-bridge/internal/page"
+)
+
+type mockFetcher struct {
+	issues map[string]*linearapi.Issue
+}
+
+func (m *mockFetcher) FetchIssue(_ context.Context, identifier string) (*linearapi.Issue, error) {
+	return m.issues[identifier], nil
+}
+
+func (m *mockFetcher) FetchPublicIssues(_ context.Context, _ string, gate linearapi.GateMode, first int) ([]*linearapi.Issue, error) {
+	var issues []*linearapi.Issue
+	for _, issue := range m.issues {
+		if gate.IsPublic(issue) {
+			issues = append(issues, issue)
+		}
+	}
+	slices.SortFunc(issues, func(a, b *linearapi.Issue) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+	return issues[:min(first, len(issues))], nil
+}
+
+func newTestServer(t *testing.T, issues ...*linearapi.Issue) *server {
+	t.Helper()
+	fetcher := &mockFetcher{issues: make(map[string]*linearapi.Issue)}
+	for _, issue := range issues {
+		fetcher.issues[issue.Identifier] = issue
+	}
+	renderer, err := page.NewRenderer("MIR", "")
+	if err != nil {
+		t.Fatalf("NewRenderer: %v", err)
+	}
+	return &server{
+		cache:             cache.New(fetcher, time.Minute),
+		publicIssues:      cache.NewPublicList(fetcher, "MIR", linearapi.GateAllowlist, feedSize, 0),
+		renderer:          renderer,
+		teamKey:           "MIR",
+		identifierPattern: newIdentifierPattern("MIR"),
+		listTimeout:       defaultListTimeout,
+	}
+}
+
+func publicIssue(identifier, title string) *linearapi.Issue {
+	return &linearapi.Issue{
+		ID:          "uuid-" + identifier,
+		Identifier:  identifier,
+		Title:       title,
+		Description: "Some **description**.",
+		State:       linearapi.State{Name: "Todo", Color: "#e2e2e2", Type: "unstarted"},
+		Labels:      []linearapi.Label{{Name: "public", Color: "#5e6ad2"}},
+		UpdatedAt:   time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestIdentifierPattern(t *testing.T) {
+	re := newIdentifierPattern("MIR", "mira")
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"MIR-1", true},
+		{"MIRA-42", true},
+		{"MIR-1234567", true},
+		{"MIR-0", false},
+		{"MIR-01", false},
+		{"MIR--1", false},
+		{"MIR-", false},
+		{"MIR-1/extra", false},
+		{"MIR-1A", false},
+		{"XMIR-1", false},
+		{"MIRAB-1", false},
+		{"WEB-1", false},
+		{"mir-1", false},
+	}
+	for _, tt := range tests {
+		if got := re.MatchString(tt.input); got != tt.want {
+			t.Errorf("MatchString(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestIssueAdversarialPaths(t *testing.T) {
+	srv := newTestServer(t, publicIssue("MIR-1", "One"))
+	mux := srv.routes()
+
+	for _, path := range []string{"/MIR-01", "/MIR--1", "/MIR-1/extra", "/WEB-1"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status = %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestIssueHead(t *testing.T) {
+	srv := newTestServer(t, publicIssue("MIR-42", "Head Test"))
+	mux := srv.routes()
+
+	req := httptest.NewRequest(http.MethodHead, "/MIR-42", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if rr.Header().Get("ETag") == "" {
+		t.Error("expected ETag header")
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("expected empty body for HEAD, got %d bytes", rr.Body.Len())
+	}
+
+	get := httptest.NewRecorder()
+	mux.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/MIR-42", nil))
+	if get.Header().Get("ETag") != rr.Header().Get("ETag") {
+		t.Errorf("GET ETag = %q, HEAD ETag = %q", get.Header().Get("ETag"), rr.Header().Get("ETag"))
+	}
+	if get.Header().Get("Content-Length") != rr.Header().Get("Content-Length") {
+		t.Errorf("GET Content-Length = %q, HEAD Content-Length = %q", get.Header().Get("Content-Length"), rr.Header().Get("Content-Length"))
+	}
+}
+
+func TestIssueHeadNotFound(t *testing.T) {
+	srv := newTestServer(t)
+	mux := srv.routes()
+
+	for _, path := range []string{"/MIR-999", "/not-an-issue"} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodHead, path, nil))
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("HEAD %s status = %d, want %d", path, rr.Code, http.StatusNotFound)
+		}
+		if rr.Body.Len() != 0 {
+			t.Errorf("HEAD %s: expected empty body, got %d bytes", path, rr.Body.Len())
+		}
+	}
+}
+
+func TestIssueIfNoneMatch(t *testing.T) {
+	srv := newTestServer(t, publicIssue("MIR-42", "Conditional"))
+	mux := srv.routes()
+
+	first := httptest.NewRecorder()
+	mux.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/MIR-42", nil))
+	etag := first.Header().Get("ETag")
+
+	req := httptest.NewRequest(http.MethodGet, "/MIR-42", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusNotModified)
+	}
+}
+
+func TestIssueETagCoversRenderedPage(t *testing.T) {
+	issue := publicIssue("MIR-42", "Conditional")
+	srv := newTestServer(t, issue)
+	mux := srv.routes()
+
+	first := httptest.NewRecorder()
+	mux.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/MIR-42", nil))
+	etag := first.Header().Get("ETag")
+
+	// Reactions and comments change the page without touching UpdatedAt.
+	issue.Reactions = []linearapi.Reaction{{Emoji: "👍", Count: 3}}
+	issue.Comments = []linearapi.Comment{{Author: "alice", Body: "Thanks!"}}
+
+	req := httptest.NewRequest(http.MethodGet, "/MIR-42", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d for a page that changed", rr.Code, http.StatusOK)
+	}
+	if rr.Header().Get("ETag") == etag {
+		t.Errorf("ETag %s unchanged after the page changed", etag)
+	}
+}
+
+func TestIssueMarkdownExport(t *testing.T) {
+	srv := newTestServer(t, publicIssue("MIR-42", "Export Me"))
+	mux := srv.routes()
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-42.md", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/markdown") {
+		t.Errorf("Content-Type = %q, want text/markdown", ct)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "# MIR-42: Export Me") {
+		t.Errorf("body missing title heading:\n%s", body)
+	}
+	if !strings.Contains(body, "Some **description**.") {
+		t.Errorf("body missing raw description:\n%s", body)
+	}
+}
+
+func TestIssueMarkdownExportNotPublic(t *testing.T) {
+	issue := publicIssue("MIR-7", "Private")
+	issue.Labels = nil
+	srv := newTestServer(t, issue)
+
+	rr := httptest.NewRecorder()
+	srv.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-7.md", nil))
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if strings.Contains(rr.Body.String(), "Private") {
+		t.Error("markdown export leaked the title of a non-public issue")
+	}
+}
+
+func TestIssueDenylistGate(t *testing.T) {
+	open := publicIssue("MIR-1", "Open By Default")
+	open.Labels = nil
+	private := publicIssue("MIR-2", "Hidden Plans")
+	private.Labels = []linearapi.Label{{Name: "private"}}
+
+	srv := newTestServer(t, open, private)
+	srv.gate = linearapi.GateDenylist
+	mux := srv.routes()
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-1", nil))
+	if !strings.Contains(rr.Body.String(), "Open By Default") {
+		t.Errorf("unlabeled issue should render in denylist mode:\n%s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-2", nil))
+	if strings.Contains(rr.Body.String(), "Hidden Plans") {
+		t.Error("private issue rendered in denylist mode")
+	}
+	if !strings.Contains(rr.Body.String(), "not currently shared publicly") {
+		t.Errorf("expected stub page for private issue:\n%s", rr.Body.String())
+	}
+}
+
+func TestIssueCacheStatusHeader(t *testing.T) {
+	srv := newTestServer(t, publicIssue("MIR-42", "Cached"))
+	mux := srv.routes()
+
+	for _, want := range []string{"MISS", "HIT"} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-42", nil))
+		if got := rr.Header().Get("X-Cache"); got != want {
+			t.Errorf("X-Cache = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestAdminCache(t *testing.T) {
+	private := publicIssue("MIR-2", "Private")
+	private.Labels = nil
+	srv := newTestServer(t, publicIssue("MIR-1", "Public"), private)
+	srv.adminToken = "admin-secret"
+	mux := srv.routes()
+
+	for _, path := range []string{"/MIR-1", "/MIR-2"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/cache", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("status without token = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/cache", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	var entries []cacheEntryJSON
+	if err := json.Unmarshal(rr.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %s", len(entries), rr.Body.String())
+	}
+	if entries[0].Identifier != "MIR-1" || !entries[0].Found || !entries[0].Public {
+		t.Errorf("entries[0] = %+v, want found public MIR-1", entries[0])
+	}
+	if entries[1].Identifier != "MIR-2" || !entries[1].Found || entries[1].Public {
+		t.Errorf("entries[1] = %+v, want found non-public MIR-2", entries[1])
+	}
+	for _, e := range entries {
+		if e.AgeSeconds < 0 {
+			t.Errorf("%s age = %v, want non-negative", e.Identifier, e.AgeSeconds)
+		}
+	}
+}
+
+func TestAdminCacheDelete(t *testing.T) {
+	srv := newTestServer(t, publicIssue("MIR-1", "One"), publicIssue("MIR-2", "Two"))
+	srv.adminToken = "admin-secret"
+	mux := srv.routes()
+
+	for _, id := range []string{"MIR-1", "MIR-2"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/"+id, nil))
+	}
+	del := func(path string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodDelete, path, nil)
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("DELETE %s status = %d, want %d", path, rr.Code, http.StatusNoContent)
+		}
+	}
+
+	del("/admin/cache/mir-1")
+	if n := srv.cache.Stats().Entries; n != 1 {
+		t.Errorf("after deleting MIR-1, %d entries cached, want 1", n)
+	}
+	del("/admin/cache")
+	if n := srv.cache.Stats().Entries; n != 0 {
+		t.Errorf("after clearing, %d entries cached, want 0", n)
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/admin/cache", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated DELETE status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAdminStats(t *testing.T) {
+	srv := newTestServer(t, publicIssue("MIR-1", "Public"))
+	srv.adminToken = "admin-secret"
+	mux := srv.routes()
+
+	get := func() statsJSON {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+		}
+		var stats statsJSON
+		if err := json.Unmarshal(rr.Body.Bytes(), &stats); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return stats
+	}
+
+	if stats := get(); stats.LastSuccess != nil {
+		t.Errorf("last_success before any fetch = %v, want null", stats.LastSuccess)
+	}
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/MIR-1", nil))
+	if stats := get(); stats.LastSuccess == nil || time.Since(*stats.LastSuccess) > time.Minute {
+		t.Errorf("last_success after fetch = %v, want recent", stats.LastSuccess)
+	}
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/MIR-1", nil))
+	if stats := get(); stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("hits, misses, entries = %d, %d, %d; want 1, 1, 1", stats.Hits, stats.Misses, stats.Entries)
+	}
+}
+
+func TestAdminCacheDisabledWithoutToken(t *testing.T) {
+	srv := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/cache", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rr := httptest.NewRecorder()
+	srv.routes().ServeHTTP(rr, req)
+
+	if strings.HasPrefix(rr.Header().Get("Content-Type"), "application/json") {
+		t.Error("admin endpoint should not be served without ADMIN_TOKEN")
+	}
+}
+
+func TestPreview(t *testing.T) {
+	srv := newTestServer(t)
+	srv.dev = true
+
+	rr := httptest.NewRecorder()
+	srv.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/preview", strings.NewReader("**bold**")))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if !strings.Contains(rr.Body.String(), "<strong>bold</strong>") {
+		t.Errorf("body should contain <strong>bold</strong>, got:\n%s", rr.Body.String())
+	}
+}
+
+func TestPreviewDisabledOutsideDev(t *testing.T) {
+	srv := newTestServer(t)
+
+	rr := httptest.NewRecorder()
+	srv.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/preview", strings.NewReader("**bold**")))
+
+	if rr.Code == http.StatusOK || strings.Contains(rr.Body.String(), "<strong>bold</strong>") {
+		t.Errorf("POST /preview status = %d; should not be served without DEV", rr.Code)
+	}
+}
+
+func TestFavicon(t *testing.T) {
+	srv := newTestServer(t)
+	mux := srv.routes()
+
+	for _, path := range []string{"/favicon.ico", "/favicon.svg", "/apple-touch-icon.png"} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("GET %s status = %d, want %d", path, rr.Code, http.StatusOK)
+		}
+		if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "image/") {
+			t.Errorf("GET %s Content-Type = %q, want image/*", path, ct)
+		}
+		if cc := rr.Header().Get("Cache-Control"); !strings.Contains(cc, "max-age=") {
+			t.Errorf("GET %s Cache-Control = %q, want long max-age", path, cc)
+		}
+	}
+}
+
+func TestNewHTTPServer(t *testing.T) {
+	t.Setenv("HTTP_READ_TIMEOUT", "7s")
+	t.Setenv("HTTP_MAX_HEADER_BYTES", "4096")
+
+	hc, err := loadHTTPConfig()
+	if err != nil {
+		t.Fatalf("loadHTTPConfig: %v", err)
+	}
+	hs := newHTTPServer(hc, http.NotFoundHandler())
+
+	if hs.ReadTimeout != 7*time.Second {
+		t.Errorf("ReadTimeout = %v, want 7s", hs.ReadTimeout)
+	}
+	if hs.MaxHeaderBytes != 4096 {
+		t.Errorf("MaxHeaderBytes = %d, want 4096", hs.MaxHeaderBytes)
+	}
+	for name, d := range map[string]time.Duration{
+		"ReadHeaderTimeout": hs.ReadHeaderTimeout,
+		"WriteTimeout":      hs.WriteTimeout,
+		"IdleTimeout":       hs.IdleTimeout,
+	} {
+		if d <= 0 {
+			t.Errorf("%s = %v, want a default timeout", name, d)
+		}
+	}
+}
+
+func TestIssueCard(t *testing.T) {
+	private := publicIssue("MIR-7", "Private")
+	private.Labels = nil
+	srv := newTestServer(t, publicIssue("MIR-42", "Card Title"), private)
+	mux := srv.routes()
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-42.svg", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "image/svg+xml" {
+		t.Errorf("Content-Type = %q, want image/svg+xml", ct)
+	}
+	if !strings.Contains(rr.Body.String(), "Card Title") {
+		t.Errorf("card missing title:\n%s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-7.svg", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("non-public card status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if strings.Contains(rr.Body.String(), "Private") {
+		t.Error("card leaked the title of a non-public issue")
+	}
+}
+
+func TestJSONFeed(t *testing.T) {
+	private := publicIssue("MIR-7", "Private")
+	private.Labels = nil
+	srv := newTestServer(t, publicIssue("MIR-42", "Feed Title"), private)
+	mux := srv.routes()
+
+	req := httptest.NewRequest(http.MethodGet, "/feed.json", nil)
+	req.Host = "issues.example.com"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/feed+json" {
+		t.Errorf("Content-Type = %q, want application/feed+json", ct)
+	}
+
+	var feed struct {
+		Version string `json:"version"`
+		Items   []struct {
+			ID           string    `json:"id"`
+			URL          string    `json:"url"`
+			Title        string    `json:"title"`
+			ContentHTML  string    `json:"content_html"`
+			DateModified time.Time `json:"date_modified"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("decode feed: %v\n%s", err, rr.Body.String())
+	}
+	if feed.Version != "https://jsonfeed.org/version/1.1" {
+		t.Errorf("version = %q", feed.Version)
+	}
+	if len(feed.Items) != 1 {
+		t.Fatalf("got %d items, want 1 (non-public issues must be left out)", len(feed.Items))
+	}
+	item := feed.Items[0]
+	if item.ID != "MIR-42" || item.URL != "https://issues.example.com/MIR-42" {
+		t.Errorf("item id/url = %q %q, want MIR-42 https://issues.example.com/MIR-42", item.ID, item.URL)
+	}
+	if !strings.Contains(item.ContentHTML, "<strong>description</strong>") {
+		t.Errorf("content_html = %q, want rendered description", item.ContentHTML)
+	}
+	if !item.DateModified.Equal(time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("date_modified = %v", item.DateModified)
+	}
+}
+
+// slowListFetcher lists no issues after delay, or fails when ctx is done
+// first.
+type slowListFetcher struct {
+	delay time.Duration
+}
+
+func (f slowListFetcher) FetchPublicIssues(ctx context.Context, _ string, _ linearapi.GateMode, _ int) ([]*linearapi.Issue, error) {
+	select {
+	case <-time.After(f.delay):
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestJSONFeedListTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		delay      time.Duration
+		wantStatus int
+	}{
+		{"within LIST_TIMEOUT", 20 * time.Millisecond, http.StatusOK},
+		{"beyond LIST_TIMEOUT", time.Second, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			srv.publicIssues = cache.NewPublicList(slowListFetcher{tt.delay}, "MIR", linearapi.GateAllowlist, feedSize, 0)
+			srv.listTimeout = 200 * time.Millisecond
+
+			rr := httptest.NewRecorder()
+			srv.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/feed.json", nil))
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestJSONFeedSummary(t *testing.T) {
+	long := publicIssue("MIR-42", "Long")
+	long.Description = "First **line**.\n\n" + strings.Repeat("word ", 200) + "END"
+	srv := newTestServer(t, long)
+	mux := srv.routes()
+
+	type item struct {
+		ContentHTML string `json:"content_html"`
+		ContentText string `json:"content_text"`
+		Summary     string `json:"summary"`
+	}
+	get := func(target string) item {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s status = %d, want %d", target, rr.Code, http.StatusOK)
+		}
+		var feed struct {
+			Items []item `json:"items"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+			t.Fatalf("decode %s: %v", target, err)
+		}
+		if len(feed.Items) != 1 {
+			t.Fatalf("%s: got %d items, want 1", target, len(feed.Items))
+		}
+		return feed.Items[0]
+	}
+
+	full := get("/feed.json")
+	if !strings.Contains(full.ContentHTML, "END") || full.Summary != "" {
+		t.Errorf("default item = %+v, want full content_html and no summary", full)
+	}
+
+	summary := get("/feed.json?summary=1")
+	if summary.ContentHTML != "" {
+		t.Errorf("summary content_html = %q, want it omitted", summary.ContentHTML)
+	}
+	if !strings.HasPrefix(summary.Summary, "First **line**. word word") || !strings.HasSuffix(summary.Summary, "…") {
+		t.Errorf("summary = %q, want a truncated excerpt", summary.Summary)
+	}
+	if strings.Contains(summary.Summary, "END") {
+		t.Error("summary includes the end of the description")
+	}
+	if summary.ContentText != summary.Summary {
+		t.Errorf("content_text = %q, want the excerpt", summary.ContentText)
+	}
+}
+
+func TestJSONFeedContentModes(t *testing.T) {
+	long := publicIssue("MIR-42", "Long")
+	long.Description = "First **line**.\n\n" + strings.Repeat("word ", 200) + "END"
+
+	tests := []struct {
+		content         page.FeedContent
+		wantHTML        string
+		wantSummary     string
+		wantContentText string
+	}{
+		{content: page.FeedFull, wantHTML: "<strong>line</strong>"},
+		{content: page.FeedExcerpt, wantSummary: "First **line**. word", wantContentText: "First **line**. word"},
+		{content: page.FeedNone, wantContentText: "MIR-42: Long"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.content), func(t *testing.T) {
+			srv := newTestServer(t, long)
+			srv.feedContent = tt.content
+
+			rr := httptest.NewRecorder()
+			srv.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/feed.json", nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+			}
+			var feed struct {
+				Items []struct {
+					ContentHTML string `json:"content_html"`
+					ContentText string `json:"content_text"`
+					Summary     string `json:"summary"`
+				} `json:"items"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(feed.Items) != 1 {
+				t.Fatalf("got %d items, want 1", len(feed.Items))
+			}
+			item := feed.Items[0]
+
+			if tt.wantHTML == "" && item.ContentHTML != "" || !strings.Contains(item.ContentHTML, tt.wantHTML) {
+				t.Errorf("content_html = %q, want %q", item.ContentHTML, tt.wantHTML)
+			}
+			if tt.wantSummary == "" && item.Summary != "" || !strings.HasPrefix(item.Summary, tt.wantSummary) {
+				t.Errorf("summary = %q, want prefix %q", item.Summary, tt.wantSummary)
+			}
+			if tt.wantContentText == "" && item.ContentText != "" || !strings.HasPrefix(item.ContentText, tt.wantContentText) {
+				t.Errorf("content_text = %q, want prefix %q", item.ContentText, tt.wantContentText)
+			}
+			if tt.content == page.FeedExcerpt && (len([]rune(item.Summary)) > 300 || strings.Contains(item.Summary, "END")) {
+				t.Errorf("summary is %d characters, want a short excerpt", len([]rune(item.Summary)))
+			}
+		})
+	}
+}
+
+func TestJSONFeedConditionalGet(t *testing.T) {
+	srv := newTestServer(t, publicIssue("MIR-42", "Feed Title"))
+	mux := srv.routes()
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/feed.json", nil))
+	lastMod := rr.Header().Get("Last-Modified")
+	if lastMod != "Wed, 15 Jan 2025 12:00:00 GMT" {
+		t.Fatalf("Last-Modified = %q, want the issue's UpdatedAt", lastMod)
+	}
+
+	tests := []struct {
+		since string
+		want  int
+	}{
+		{lastMod, http.StatusNotModified},
+		{"Thu, 16 Jan 2025 00:00:00 GMT", http.StatusNotModified},
+		{"Wed, 15 Jan 2025 11:59:59 GMT", http.StatusOK},
+		{"not a date", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/feed.json", nil)
+		req.Header.Set("If-Modified-Since", tt.since)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("If-Modified-Since %q: status = %d, want %d", tt.since, rr.Code, tt.want)
+		}
+		if tt.want == http.StatusNotModified && rr.Body.Len() != 0 {
+			t.Errorf("If-Modified-Since %q: 304 carried a body", tt.since)
+		}
+	}
+}
+
+func TestBareIssueNumber(t *testing.T) {
+	tests := []struct {
+		name         string
+		enabled      bool
+		teamKeys     []string
+		path         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"single team", true, []string{"mir"}, "/42", http.StatusFound, "/MIR-42"},
+		{"keeps suffix", true, []string{"MIR"}, "/42.md", http.StatusFound, "/MIR-42.md"},
+		{"keeps query", true, []string{"MIR"}, "/42?utm_source=chat", http.StatusFound, "/MIR-42?utm_source=chat"},
+		{"leading zero", true, []string{"MIR"}, "/042", http.StatusNotFound, ""},
+		{"multiple teams", true, []string{"MIR", "WEB"}, "/42", http.StatusNotFound, ""},
+		{"disabled", false, []string{"MIR"}, "/42", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, publicIssue("MIR-42", "Bare"))
+			srv.bareNumberTeam = bareNumberTeam(tt.enabled, tt.teamKeys...)
+
+			rr := httptest.NewRecorder()
+			srv.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if loc := rr.Header().Get("Location"); loc != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", loc, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestIssueAliases(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"aliased", "/MIR-42", http.StatusMovedPermanently, "/NEW-7"},
+		{"lowercase", "/mir-42", http.StatusMovedPermanently, "/NEW-7"},
+		{"keeps suffix and query", "/MIR-42.md?ref=x", http.StatusMovedPermanently, "/NEW-7.md?ref=x"},
+		{"not aliased", "/MIR-43", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, publicIssue("MIR-43", "Stayed put"))
+			srv.aliases = map[string]string{"MIR-42": "NEW-7"}
+
+			rr := httptest.NewRecorder()
+			srv.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if loc := rr.Header().Get("Location"); loc != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", loc, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestCanonicalRedirect(t *testing.T) {
+	moved := publicIssue("MIR-42", "Launch")
+	moved.Attachments = []linearapi.Attachment{{URL: "https://miren.dev/blog/launch", Title: "Public URL"}}
+	srv := newTestServer(t, moved, publicIssue("MIR-43", "No post"))
+	srv.renderer.SetCanonicalAttachment("Public URL")
+	srv.canonicalRedirect = true
+	mux := srv.routes()
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-42", nil))
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != "https://miren.dev/blog/launch" {
+		t.Errorf("got %d to %q, want %d to the blog post", rr.Code, rr.Header().Get("Location"), http.StatusFound)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-43", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("issue without a canonical attachment: status = %d, want %d", rr.Code, http.StatusOK)
+	}
+}
+
+type slowFetcher struct {
+	release chan struct{}
+	started chan string
+}
+
+func (f *slowFetcher) FetchIssue(_ context.Context, identifier string) (*linearapi.Issue, error) {
+	f.started <- identifier
+	<-f.release
+	return publicIssue(identifier, "Slow"), nil
+}
+
+func TestIssueFetchLimit(t *testing.T) {
+	fetcher := &slowFetcher{release: make(chan struct{}), started: make(chan string, 2)}
+	srv := newTestServer(t)
+	srv.cache = cache.New(fetcher, time.Minute)
+	srv.cache.SetFetchLimit(1, 20*time.Millisecond)
+	mux := srv.routes()
+
+	first := make(chan int)
+	go func() {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-1", nil))
+		first <- rr.Code
+	}()
+	<-fetcher.started
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-2", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("second request status = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("503 response missing Retry-After")
+	}
+
+	close(fetcher.release)
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("first request status = %d, want %d", code, http.StatusOK)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-2", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("retry after slot freed: status = %d, want %d", rr.Code, http.StatusOK)
+	}
+}
+
+func TestTrailingSlashRedirect(t *testing.T) {
+	srv := newTestServer(t, publicIssue("MIR-42", "Slash"))
+	mux := srv.routes()
+
+	tests := []struct {
+		path         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"/MIR-42/", http.StatusMovedPermanently, "/MIR-42"},
+		{"/mir-42/?ref=chat", http.StatusMovedPermanently, "/mir-42?ref=chat"},
+		{"/MIR-42/extra/", http.StatusNotFound, ""},
+		{"/WEB-1/", http.StatusNotFound, ""},
+		{"/no/such/page", http.StatusNotFound, ""},
+		{"/static/style.css", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rr.Code != tt.wantStatus {
+			t.Errorf("GET %s: status = %d, want %d", tt.path, rr.Code, tt.wantStatus)
+		}
+		if loc := rr.Header().Get("Location"); loc != tt.wantLocation {
+			t.Errorf("GET %s: Location = %q, want %q", tt.path, loc, tt.wantLocation)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/static/", nil))
+	if rr.Code == http.StatusMovedPermanently {
+		t.Errorf("GET /static/ was redirected to %q", rr.Header().Get("Location"))
+	}
+}
+
+func TestIssueStaleBanner(t *testing.T) {
+	srv := newTestServer(t, publicIssue("MIR-42", "Banner"))
+	srv.staleBanner = time.Millisecond
+	mux := srv.routes()
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/MIR-42", nil))
+	time.Sleep(5 * time.Millisecond)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-42", nil))
+	if !strings.Contains(rr.Body.String(), "outdated-banner") {
+		t.Error("page served from an old cache entry has no outdated banner")
+	}
+	staleETag := rr.Header().Get("ETag")
+
+	srv.staleBanner = 0
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/MIR-42", nil))
+	if strings.Contains(rr.Body.String(), "outdated-banner") {
+		t.Error("banner shown with the threshold disabled")
+	}
+	if etag := rr.Header().Get("ETag"); etag == staleETag {
+		t.Errorf("bannered page reused the fresh ETag %s", etag)
+	}
+}